@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/gclient"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/fainc/gfe/crypt"
+)
+
+// 响应格式，与 response.FormatJSON / response.FormatMsgPack 一致
+const (
+	FormatJSON    = "json"
+	FormatMsgPack = "msgPack"
+)
+
+// Options 客户端配置
+type Options struct {
+	Format          string            // 协商的响应格式，默认 json
+	Decryptor       crypt.Decryptor   // 解密 encrypted payload，如 crypt.NewAESGCM 或 crypt.NewSM2Decryptor
+	ClientKey       *ecdsa.PrivateKey // 通过 X-Client-Public-Key 请求 ECIES 加密响应，并以该私钥解密
+	Verifiers       []crypt.Verifier  // 配置后校验响应签名，未签名或验签失败的响应返回错误
+	MaxSignatureAge time.Duration     // 响应签名时间戳允许的偏差，0 不校验
+}
+
+// Client gfe 服务客户端，解析 {ok,payload,error,encrypted} 响应信封
+type Client struct {
+	http *gclient.Client
+	opts Options
+}
+
+// New 基于 gclient 创建客户端，c 为空时使用 g.Client()，可预先设置 Prefix、Header 等
+func New(c *gclient.Client, opts ...Options) *Client {
+	if c == nil {
+		c = g.Client()
+	}
+	client := &Client{http: c}
+	if len(opts) > 0 {
+		client.opts = opts[0]
+	}
+	if client.opts.Format == "" {
+		client.opts.Format = FormatJSON
+	}
+	return client
+}
+
+// Result 响应信封，payload 保持原始编码，由 Decode 解析
+type Result struct {
+	Ok        bool
+	Encrypted bool
+	Error     error
+	format    string
+	payload   []byte
+	decryptor crypt.Decryptor
+}
+
+type errorBody struct {
+	Code    int         `json:"code" msgpack:"code"`
+	Message string      `json:"message" msgpack:"message"`
+	Detail  interface{} `json:"detail" msgpack:"detail"`
+	Errors  interface{} `json:"errors" msgpack:"errors"` // problem details 的字段错误
+	Title   string      `json:"title" msgpack:"title"`   // problem details 的标题
+}
+
+// Do 发送请求并解析响应信封，业务错误转换为 gerror 错误码错误
+func (rec *Client) Do(ctx context.Context, method, url string, data ...interface{}) (result *Result, err error) {
+	c := rec.http.Clone()
+	if rec.opts.Format == FormatMsgPack {
+		c.SetHeader("Accept", "application/x-msgpack")
+	} else {
+		c.SetHeader("Accept", "application/json")
+	}
+	if rec.opts.ClientKey != nil {
+		header, err := crypt.ClientPublicKeyHeader(&rec.opts.ClientKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		c.SetHeader(crypt.HeaderClientPublicKey, header)
+	}
+	res, err := c.DoRequest(ctx, method, url, data...)
+	if err != nil {
+		return
+	}
+	defer res.Close()
+	body := res.ReadAll()
+	if len(rec.opts.Verifiers) > 0 {
+		if err = crypt.VerifyResponse(res.Response, body, rec.opts.MaxSignatureAge, rec.opts.Verifiers...); err != nil {
+			return
+		}
+	}
+	format := FormatJSON
+	if strings.Contains(res.Header.Get("Content-Type"), "msgpack") {
+		format = FormatMsgPack
+	}
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "application/problem+json") {
+		return rec.problem(body)
+	}
+	var envelope struct {
+		Ok        bool       `json:"ok" msgpack:"ok"`
+		Payload   rawPayload `json:"payload" msgpack:"payload"`
+		Error     *errorBody `json:"error" msgpack:"error"`
+		Encrypted bool       `json:"encrypted" msgpack:"encrypted"`
+	}
+	if err = unmarshal(format, body, &envelope); err != nil || !envelope.Ok && envelope.Error == nil {
+		return nil, gerror.NewCodef(gcode.CodeInvalidRequest, "unexpected response %d: %s", res.StatusCode, truncate(body))
+	}
+	result = &Result{Ok: envelope.Ok, Encrypted: envelope.Encrypted, format: format, payload: envelope.Payload, decryptor: rec.decryptor()}
+	if !envelope.Ok {
+		result.Error = envelope.Error.error()
+	}
+	return
+}
+
+// problem 解析 RFC 9457 problem details 错误响应
+func (rec *Client) problem(body []byte) (*Result, error) {
+	var e errorBody
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	if e.Message == "" {
+		e.Message = e.Title
+	}
+	if e.Errors != nil {
+		e.Detail = e.Errors
+	}
+	return &Result{Error: e.error()}, nil
+}
+
+func (rec *Client) decryptor() crypt.Decryptor {
+	if rec.opts.Decryptor == nil && rec.opts.ClientKey != nil {
+		return crypt.NewECDHDecryptor(rec.opts.ClientKey, "")
+	}
+	return rec.opts.Decryptor
+}
+
+// Decode 将 payload 解析到 pointer，加密 payload 先解密
+func (rec *Result) Decode(pointer interface{}) error {
+	if rec.Error != nil {
+		return rec.Error
+	}
+	if !rec.Encrypted {
+		if len(rec.payload) == 0 {
+			return nil
+		}
+		return unmarshal(rec.format, rec.payload, pointer)
+	}
+	if rec.decryptor == nil {
+		return gerror.NewCode(gcode.CodeMissingConfiguration, "decryptor of the encrypted payload is not configured")
+	}
+	var env crypt.Envelope
+	if err := unmarshal(rec.format, rec.payload, &env); err != nil {
+		return err
+	}
+	if env.Alg != rec.decryptor.Alg() {
+		return gerror.NewCodef(gcode.CodeNotSupported, "unsupported envelope alg %s", env.Alg)
+	}
+	return crypt.Open(rec.decryptor, &env, pointer)
+}
+
+// Do 发送请求并将 payload 解析为 T，业务错误以 gerror 错误码返回，可用 gerror.Code(err) 获取 code 与 detail
+func Do[T any](ctx context.Context, c *Client, method, url string, data ...interface{}) (*T, error) {
+	result, err := c.Do(ctx, method, url, data...)
+	if err != nil {
+		return nil, err
+	}
+	payload := new(T)
+	if err = result.Decode(payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Get GET 请求并将 payload 解析为 T
+func Get[T any](ctx context.Context, c *Client, url string, data ...interface{}) (*T, error) {
+	return Do[T](ctx, c, "GET", url, data...)
+}
+
+// Post POST 请求并将 payload 解析为 T
+func Post[T any](ctx context.Context, c *Client, url string, data ...interface{}) (*T, error) {
+	return Do[T](ctx, c, "POST", url, data...)
+}
+
+func (e *errorBody) error() error {
+	return gerror.NewCode(gcode.New(e.Code, e.Message, e.Detail), e.Message)
+}
+
+// rawPayload 保留 payload 原始编码，JSON 与 MsgPack 通用
+type rawPayload []byte
+
+func (p *rawPayload) UnmarshalJSON(b []byte) error {
+	if string(b) != "null" {
+		*p = append((*p)[:0], b...)
+	}
+	return nil
+}
+
+func (p *rawPayload) DecodeMsgpack(dec *msgpack.Decoder) error {
+	raw, err := dec.DecodeRaw()
+	if err != nil {
+		return err
+	}
+	*p = rawPayload(raw)
+	return nil
+}
+
+func unmarshal(format string, data []byte, pointer interface{}) error {
+	if format == FormatMsgPack {
+		return msgpack.Unmarshal(data, pointer)
+	}
+	return json.Unmarshal(data, pointer)
+}
+
+func truncate(body []byte) string {
+	if len(body) > 256 {
+		return fmt.Sprintf("%s...", body[:256])
+	}
+	return string(body)
+}
@@ -0,0 +1,113 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+)
+
+type ecdhDecryptor struct {
+	keyID   string
+	private *ecdh.PrivateKey
+}
+
+// NewECDHDecryptor ECDH(P-256) + AES-256-GCM 解密器，可直接复用 JWT 的 ECDSA 私钥
+func NewECDHDecryptor(private *ecdsa.PrivateKey, keyID string) Decryptor {
+	pri, err := private.ECDH()
+	if err != nil {
+		panic(err.Error())
+	}
+	return &ecdhDecryptor{keyID: keyID, private: pri}
+}
+
+func (rec *ecdhDecryptor) Alg() string {
+	return AlgECDH
+}
+
+func (rec *ecdhDecryptor) Decrypt(env *Envelope) (plain []byte, err error) {
+	if rec.keyID != "" && env.KeyID != "" && env.KeyID != rec.keyID {
+		return nil, errors.New("unknown key id")
+	}
+	ephemeral, err := decodeField(env.EphemeralKey)
+	if err != nil {
+		return
+	}
+	pub, err := ecdh.P256().NewPublicKey(ephemeral)
+	if err != nil {
+		return
+	}
+	shared, err := rec.private.ECDH(pub)
+	if err != nil {
+		return
+	}
+	return openGCM(aes.NewCipher, deriveKey(shared), env)
+}
+
+// SealECDH 使用服务端公钥加密，供客户端或测试构造请求信封
+func SealECDH(public *ecdsa.PublicKey, keyID string, plain []byte) (env *Envelope, err error) {
+	pub, err := public.ECDH()
+	if err != nil {
+		return
+	}
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return
+	}
+	shared, err := ephemeral.ECDH(pub)
+	if err != nil {
+		return
+	}
+	env, err = sealGCM(aes.NewCipher, deriveKey(shared), plain)
+	if err != nil {
+		return
+	}
+	env.Alg = AlgECDH
+	env.KeyID = keyID
+	env.EphemeralKey = encodeField(ephemeral.PublicKey().Bytes())
+	return
+}
+
+func deriveKey(shared []byte) []byte {
+	sum := sha256.Sum256(shared)
+	return sum[:]
+}
+
+// ParseClientPublicKey 解析客户端公钥，支持 base64 编码的 PKIX der 或未压缩点
+func ParseClientPublicKey(s string) (pub *ecdsa.PublicKey, err error) {
+	raw, err := decodeField(s)
+	if err != nil {
+		return
+	}
+	if key, parseErr := x509.ParsePKIXPublicKey(raw); parseErr == nil {
+		if pub, ok := key.(*ecdsa.PublicKey); ok && pub.Curve.Params().Name == "P-256" {
+			return pub, nil
+		}
+		return nil, errors.New("client public key is not a P-256 key")
+	}
+	point, err := ecdh.P256().NewPublicKey(raw)
+	if err != nil {
+		return
+	}
+	der, err := x509.MarshalPKIXPublicKey(point)
+	if err != nil {
+		return
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return
+	}
+	return key.(*ecdsa.PublicKey), nil
+}
+
+// ClientPublicKeyHeader 客户端公钥请求头的值，服务端据此加密响应
+func ClientPublicKeyHeader(pub *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return encodeField(der), nil
+}
@@ -0,0 +1,57 @@
+package crypt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// HeaderClientPublicKey 客户端提供的 P-256 公钥请求头，服务端使用 ECIES 加密响应
+const HeaderClientPublicKey = "X-Client-Public-Key"
+
+const (
+	AlgECDH = "ECDH-ES+A256GCM" // P-256 临时密钥协商 + AES-256-GCM
+	AlgSM2  = "SM2+SM4GCM"      // SM2 加密随机 SM4 密钥 + SM4-GCM
+)
+
+// Envelope 加密信封，二进制字段均为标准 base64 编码
+type Envelope struct {
+	Alg          string `json:"alg" xml:"alg" msgpack:"alg"`
+	KeyID        string `json:"keyId,omitempty" xml:"keyId,omitempty" msgpack:"keyId,omitempty"`
+	EphemeralKey string `json:"ephemeralKey,omitempty" xml:"ephemeralKey,omitempty" msgpack:"ephemeralKey,omitempty"` // ECDH 临时公钥或 SM2 加密后的 SM4 密钥
+	Nonce        string `json:"nonce,omitempty" xml:"nonce,omitempty" msgpack:"nonce,omitempty"`
+	CipherText   string `json:"cipherText" xml:"cipherText" msgpack:"cipherText"`
+}
+
+// Decryptor 信封解密器，Alg 返回支持的算法标识
+type Decryptor interface {
+	Alg() string
+	Decrypt(env *Envelope) (plain []byte, err error)
+}
+
+// Sealer 信封加密器
+type Sealer interface {
+	Seal(plain []byte) (env *Envelope, err error)
+}
+
+// Open 解密信封并将 JSON 明文解析到 pointer，供客户端解密响应 payload 使用
+func Open(d Decryptor, env *Envelope, pointer interface{}) error {
+	plain, err := d.Decrypt(env)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plain, pointer)
+}
+
+var errEnvelope = errors.New("invalid envelope")
+
+func decodeField(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errEnvelope
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+func encodeField(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
+}
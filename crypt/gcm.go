@@ -0,0 +1,104 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"github.com/tjfoc/gmsm/sm4"
+)
+
+const (
+	AlgAESGCM = "A256GCM" // 共享密钥 AES-256-GCM
+	AlgSM4GCM = "SM4GCM"  // 共享密钥 SM4-GCM
+)
+
+type gcmCrypto struct {
+	alg      string
+	keyID    string
+	key      []byte
+	newBlock func(key []byte) (cipher.Block, error)
+}
+
+// NewAESGCM 共享密钥 AES-256-GCM，可同时用作响应加密和请求解密，key 必须为 32 字节
+func NewAESGCM(key []byte, keyID string) *gcmCrypto {
+	if len(key) != 32 {
+		panic("aes-256-gcm key must be 32 bytes")
+	}
+	return &gcmCrypto{alg: AlgAESGCM, keyID: keyID, key: key, newBlock: aes.NewCipher}
+}
+
+// NewSM4 共享密钥 SM4-GCM，可同时用作响应加密和请求解密，key 必须为 16 字节
+func NewSM4(key []byte, keyID string) *gcmCrypto {
+	if len(key) != sm4.BlockSize {
+		panic("sm4 key must be 16 bytes")
+	}
+	return &gcmCrypto{alg: AlgSM4GCM, keyID: keyID, key: key, newBlock: sm4.NewCipher}
+}
+
+func (rec *gcmCrypto) Alg() string {
+	return rec.alg
+}
+
+func (rec *gcmCrypto) Seal(plain []byte) (env *Envelope, err error) {
+	env, err = sealGCM(rec.newBlock, rec.key, plain)
+	if err != nil {
+		return
+	}
+	env.Alg = rec.alg
+	env.KeyID = rec.keyID
+	return
+}
+
+func (rec *gcmCrypto) Decrypt(env *Envelope) (plain []byte, err error) {
+	if env.Alg != rec.alg {
+		return nil, errors.New("unsupported alg " + env.Alg)
+	}
+	if rec.keyID != "" && env.KeyID != "" && env.KeyID != rec.keyID {
+		return nil, errors.New("unknown key id")
+	}
+	return openGCM(rec.newBlock, rec.key, env)
+}
+
+func sealGCM(newBlock func(key []byte) (cipher.Block, error), key, plain []byte) (env *Envelope, err error) {
+	block, err := newBlock(key)
+	if err != nil {
+		return
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	return &Envelope{
+		Nonce:      encodeField(nonce),
+		CipherText: encodeField(gcm.Seal(nil, nonce, plain, nil)),
+	}, nil
+}
+
+func openGCM(newBlock func(key []byte) (cipher.Block, error), key []byte, env *Envelope) (plain []byte, err error) {
+	nonce, err := decodeField(env.Nonce)
+	if err != nil {
+		return
+	}
+	cipherText, err := decodeField(env.CipherText)
+	if err != nil {
+		return
+	}
+	block, err := newBlock(key)
+	if err != nil {
+		return
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errEnvelope
+	}
+	return gcm.Open(nil, nonce, cipherText, nil)
+}
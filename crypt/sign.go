@@ -0,0 +1,177 @@
+package crypt
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 响应签名请求头
+const (
+	HeaderSignature          = "X-Signature"           // 标准 base64 签名
+	HeaderSignatureAlg       = "X-Signature-Alg"       // 签名算法
+	HeaderSignatureKeyID     = "X-Signature-Key-Id"    // 签名密钥 ID
+	HeaderSignatureTimestamp = "X-Signature-Timestamp" // 签名 Unix 时间戳(秒)
+)
+
+const (
+	AlgES256 = "ES256" // ECDSA P-256 + SHA-256，ASN.1 DER 签名
+	AlgHS256 = "HS256" // HMAC-SHA256
+)
+
+// Signer 响应签名器
+type Signer interface {
+	Alg() string
+	KeyID() string
+	Sign(data []byte) (signature []byte, err error)
+}
+
+// Verifier 响应验签器
+type Verifier interface {
+	Alg() string
+	KeyID() string
+	Verify(data, signature []byte) error
+}
+
+type ecdsaSigner struct {
+	keyID   string
+	private *ecdsa.PrivateKey
+	public  *ecdsa.PublicKey
+}
+
+// NewECDSASigner ECDSA(P-256) 签名器，可直接复用 JWT 的 ECDSA 私钥
+func NewECDSASigner(private *ecdsa.PrivateKey, keyID string) Signer {
+	return &ecdsaSigner{keyID: keyID, private: private, public: &private.PublicKey}
+}
+
+// NewECDSAVerifier ECDSA(P-256) 验签器，客户端使用服务端公钥验签
+func NewECDSAVerifier(public *ecdsa.PublicKey, keyID string) Verifier {
+	return &ecdsaSigner{keyID: keyID, public: public}
+}
+
+func (rec *ecdsaSigner) Alg() string {
+	return AlgES256
+}
+
+func (rec *ecdsaSigner) KeyID() string {
+	return rec.keyID
+}
+
+func (rec *ecdsaSigner) Sign(data []byte) ([]byte, error) {
+	if rec.private == nil {
+		return nil, errors.New("ecdsa private key missing")
+	}
+	digest := sha256.Sum256(data)
+	return ecdsa.SignASN1(rand.Reader, rec.private, digest[:])
+}
+
+func (rec *ecdsaSigner) Verify(data, signature []byte) error {
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(rec.public, digest[:], signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+type hmacSigner struct {
+	keyID  string
+	secret []byte
+}
+
+// NewHMAC HMAC-SHA256 签名验签器，按应用分配 secret 与 keyID
+func NewHMAC(secret []byte, keyID string) interface {
+	Signer
+	Verifier
+} {
+	return &hmacSigner{keyID: keyID, secret: secret}
+}
+
+func (rec *hmacSigner) Alg() string {
+	return AlgHS256
+}
+
+func (rec *hmacSigner) KeyID() string {
+	return rec.keyID
+}
+
+func (rec *hmacSigner) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, rec.secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+func (rec *hmacSigner) Verify(data, signature []byte) error {
+	expected, _ := rec.Sign(data)
+	if !hmac.Equal(expected, signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// SignedRequest 签名覆盖的请求与状态码，防止将其它接口或状态的已签名响应体重放为当前响应
+type SignedRequest struct {
+	Method string // 请求方法
+	Target string // 请求目标，即 URL.RequestURI()，如 /orders?page=1
+	Status int    // 响应 HTTP 状态码
+}
+
+// signedData 签名原文：时间戳 + "\n" + 请求方法 + "\n" + 请求目标 + "\n" + 状态码 + "\n" + 响应体
+func signedData(timestamp string, req SignedRequest, body []byte) []byte {
+	data := make([]byte, 0, len(timestamp)+len(req.Method)+len(req.Target)+8+len(body))
+	data = append(data, timestamp...)
+	data = append(data, '\n')
+	data = append(data, req.Method...)
+	data = append(data, '\n')
+	data = append(data, req.Target...)
+	data = append(data, '\n')
+	data = strconv.AppendInt(data, int64(req.Status), 10)
+	data = append(data, '\n')
+	return append(data, body...)
+}
+
+// SignHeader 对请求目标、状态码及响应体签名并写入签名请求头，body 为压缩前的序列化内容
+func SignHeader(s Signer, header http.Header, req SignedRequest, body []byte, now time.Time) error {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature, err := s.Sign(signedData(timestamp, req, body))
+	if err != nil {
+		return err
+	}
+	header.Set(HeaderSignature, encodeField(signature))
+	header.Set(HeaderSignatureAlg, s.Alg())
+	header.Set(HeaderSignatureKeyID, s.KeyID())
+	header.Set(HeaderSignatureTimestamp, timestamp)
+	return nil
+}
+
+// VerifyResponse 客户端校验响应签名，请求方法、目标及状态码取自 res，按密钥 ID 与算法选择验签器，maxAge 大于 0 时校验时间戳偏差
+// body 为解压后的响应体，服务端前置代理改写请求路径时签名无法通过
+func VerifyResponse(res *http.Response, body []byte, maxAge time.Duration, verifiers ...Verifier) error {
+	if res.Request == nil || res.Request.URL == nil {
+		return errors.New("response request missing")
+	}
+	header := res.Header
+	signature, err := decodeField(header.Get(HeaderSignature))
+	if err != nil {
+		return errors.New("invalid signature")
+	}
+	timestamp := header.Get(HeaderSignatureTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid signature timestamp")
+	}
+	if skew := time.Since(time.Unix(unix, 0)); maxAge > 0 && (skew > maxAge || skew < -maxAge) {
+		return errors.New("signature expired")
+	}
+	req := SignedRequest{Method: res.Request.Method, Target: res.Request.URL.RequestURI(), Status: res.StatusCode}
+	for _, v := range verifiers {
+		if v.Alg() == header.Get(HeaderSignatureAlg) && v.KeyID() == header.Get(HeaderSignatureKeyID) {
+			return v.Verify(signedData(timestamp, req, body), signature)
+		}
+	}
+	return errors.New("unknown signature key")
+}
@@ -0,0 +1,65 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"github.com/fainc/go-crypto/gm"
+	"github.com/tjfoc/gmsm/sm4"
+)
+
+type sm2Private interface {
+	Decrypt(cipherText []byte, mode int, asn1 ...bool) (plainText []byte, err error)
+}
+
+type sm2Decryptor struct {
+	keyID   string
+	private sm2Private
+}
+
+// NewSM2Decryptor SM2 + SM4-GCM 解密器，私钥为未经 der 编码的 hex
+func NewSM2Decryptor(privateHex string, keyID string) Decryptor {
+	return &sm2Decryptor{keyID: keyID, private: gm.NewSM2Private(privateHex)}
+}
+
+func (rec *sm2Decryptor) Alg() string {
+	return AlgSM2
+}
+
+func (rec *sm2Decryptor) Decrypt(env *Envelope) (plain []byte, err error) {
+	if rec.keyID != "" && env.KeyID != "" && env.KeyID != rec.keyID {
+		return nil, errors.New("unknown key id")
+	}
+	sealedKey, err := decodeField(env.EphemeralKey)
+	if err != nil {
+		return
+	}
+	key, err := rec.private.Decrypt(sealedKey, 0)
+	if err != nil {
+		return
+	}
+	if len(key) != sm4.BlockSize {
+		return nil, errEnvelope
+	}
+	return openGCM(sm4.NewCipher, key, env) // GCM 认证密文，篡改的信封无法解密
+}
+
+// SealSM2 使用服务端 SM2 公钥加密随机 SM4 密钥，SM4-GCM 加密明文，供客户端或测试构造请求信封
+func SealSM2(publicHex string, keyID string, plain []byte) (env *Envelope, err error) {
+	key := make([]byte, sm4.BlockSize)
+	if _, err = rand.Read(key); err != nil {
+		return
+	}
+	sealedKey, err := gm.NewSM2Public(publicHex).Encrypt(string(key), 0)
+	if err != nil {
+		return
+	}
+	env, err = sealGCM(sm4.NewCipher, key, plain)
+	if err != nil {
+		return
+	}
+	env.Alg = AlgSM2
+	env.KeyID = keyID
+	env.EphemeralKey = encodeField(sealedKey.Bytes())
+	return
+}
@@ -1,13 +1,18 @@
 module github.com/fainc/gfe
 
-go 1.18
+go 1.20
 
 require (
+	github.com/andybalholm/brotli v1.1.0
 	github.com/fainc/go-crypto v0.0.7
 	github.com/fainc/gojwt v1.0.5
+	github.com/fxamacker/cbor/v2 v2.5.0
 	github.com/gogf/gf/v2 v2.7.1
 	github.com/golang-jwt/jwt/v5 v5.2.2
+	github.com/tjfoc/gmsm v1.4.1
 	github.com/vmihailenco/msgpack/v5 v5.4.1
+	go.opentelemetry.io/otel/trace v1.14.0
+	google.golang.org/protobuf v1.33.0
 )
 
 require (
@@ -27,9 +32,10 @@ require (
 	github.com/olekukonko/tablewriter v0.0.5 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	go.opentelemetry.io/otel v1.14.0 // indirect
 	go.opentelemetry.io/otel/sdk v1.14.0 // indirect
-	go.opentelemetry.io/otel/trace v1.14.0 // indirect
+	golang.org/x/crypto v0.22.0 // indirect
 	golang.org/x/net v0.24.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
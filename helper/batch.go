@@ -0,0 +1,185 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fainc/gfe/response"
+)
+
+// BatchReq 批量请求，子请求按服务端路由及中间件链（Jwt、Lang、Response 等）执行
+type BatchReq struct {
+	g.Meta   `path:"/batch" method:"post" tags:"Batch" sm:"批量请求"`
+	Requests []BatchItemReq `json:"requests" v:"required" dc:"子请求列表"`
+}
+
+// BatchItemReq 子请求
+type BatchItemReq struct {
+	Method  string            `json:"method" v:"required|in:GET,POST,PUT,PATCH,DELETE" dc:"请求方法"`
+	Path    string            `json:"path" v:"required" dc:"请求路径，可带 query，如 /orders?page=2"`
+	Body    interface{}       `json:"body" dc:"请求体，以 JSON 提交"`
+	Headers map[string]string `json:"headers" dc:"额外请求头"`
+}
+
+// BatchRes 批量响应，顺序与子请求一致
+type BatchRes struct {
+	Responses []BatchItemRes `json:"responses" xml:"responses" msgpack:"responses"`
+}
+
+// BatchItemRes 子响应，Body 为子请求的响应信封，非 JSON 响应为字符串
+type BatchItemRes struct {
+	Status int         `json:"status" xml:"status" msgpack:"status"`
+	Body   interface{} `json:"body" xml:"body" msgpack:"body"`
+}
+
+// BatchOptions 批量请求配置
+type BatchOptions struct {
+	Concurrency    int      // 并发上限，默认 4
+	MaxRequests    int      // 子请求数量上限，默认 20
+	ForwardHeaders []string // 透传到子请求的请求头，默认 Authorization、Accept-Language、Cookie
+}
+
+type batch struct {
+	opts BatchOptions
+}
+
+// Batch 批量请求路由，如 group.Bind(helper.Batch())，子请求不可再发起批量请求
+func Batch(options ...BatchOptions) *batch {
+	opts := BatchOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.MaxRequests <= 0 {
+		opts.MaxRequests = 20
+	}
+	if opts.ForwardHeaders == nil {
+		opts.ForwardHeaders = []string{"Authorization", "Accept-Language", "Cookie"}
+	}
+	return &batch{opts: opts}
+}
+
+// Batch 并发执行子请求
+func (rec *batch) Batch(ctx context.Context, req *BatchReq) (res *BatchRes, err error) {
+	if ctx.Value(batchCtxKey{}) != nil {
+		return nil, response.StandError(ctx, "NestedBatchRequest") // 嵌套批量请求会成倍放大子请求数量
+	}
+	if len(req.Requests) > rec.opts.MaxRequests {
+		return nil, response.StandError(ctx, "TooManyBatchRequests", rec.opts.MaxRequests)
+	}
+	var (
+		r         = g.RequestFromCtx(ctx)
+		wg        sync.WaitGroup
+		semaphore = make(chan struct{}, rec.opts.Concurrency)
+	)
+	res = &BatchRes{Responses: make([]BatchItemRes, len(req.Requests))}
+	for i, item := range req.Requests {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, item BatchItemReq) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			res.Responses[i] = rec.dispatch(ctx, r.URL.Path, r.Request, item)
+		}(i, item)
+	}
+	wg.Wait()
+	return
+}
+
+// dispatch 通过服务端路由执行子请求，响应写入内存
+func (rec *batch) dispatch(ctx context.Context, batchPath string, parent *http.Request, item BatchItemReq) BatchItemRes {
+	path := strings.SplitN(item.Path, "?", 2)[0]
+	if !strings.HasPrefix(item.Path, "/") || path == batchPath {
+		return BatchItemRes{Status: http.StatusBadRequest, Body: "invalid batch path"}
+	}
+	var body io.Reader = http.NoBody // 服务端请求的 Body 不能为 nil
+	if item.Body != nil {
+		b, err := json.Marshal(item.Body)
+		if err != nil {
+			return BatchItemRes{Status: http.StatusBadRequest, Body: err.Error()}
+		}
+		body = bytes.NewReader(b)
+	}
+	sub, err := http.NewRequestWithContext(newBatchCtx(ctx), strings.ToUpper(item.Method), item.Path, body)
+	if err != nil {
+		return BatchItemRes{Status: http.StatusBadRequest, Body: err.Error()}
+	}
+	sub.Host, sub.RemoteAddr = parent.Host, parent.RemoteAddr
+	for _, key := range rec.opts.ForwardHeaders {
+		if value := parent.Header.Get(key); value != "" {
+			sub.Header.Set(key, value)
+		}
+	}
+	for key, value := range item.Headers {
+		sub.Header.Set(key, value)
+	}
+	sub.Header.Set("Accept", "application/json") // 子响应需要嵌入批量响应
+	if item.Body != nil {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	w := &batchWriter{header: http.Header{}}
+	g.RequestFromCtx(ctx).Server.ServeHTTP(w, sub)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if json.Valid(w.body.Bytes()) {
+		return BatchItemRes{Status: w.status, Body: json.RawMessage(w.body.Bytes())}
+	}
+	return BatchItemRes{Status: w.status, Body: w.body.String()}
+}
+
+type batchCtxKey struct{}
+
+// batchCtx 继承批量请求的取消信号及链路追踪，不继承其它上下文值，避免子请求复用批量请求的 *ghttp.Request
+type batchCtx struct {
+	context.Context
+	values context.Context
+}
+
+// newBatchCtx 子请求上下文，标记为批量子请求，子请求日志沿用批量请求的 TraceId
+func newBatchCtx(ctx context.Context) context.Context {
+	values := context.WithValue(context.Background(), batchCtxKey{}, true)
+	return batchCtx{Context: ctx, values: trace.ContextWithSpanContext(values, trace.SpanContextFromContext(ctx))}
+}
+
+func (c batchCtx) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// batchWriter 子请求的内存 ResponseWriter
+type batchWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *batchWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *batchWriter) Flush() {}
@@ -0,0 +1,174 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+
+	"github.com/fainc/gfe/response"
+)
+
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+var errCursor = errors.New("cursor is invalid")
+
+// CursorReq 游标分页请求参数，嵌入路由 req 使用
+type CursorReq struct {
+	Cursor string `json:"cursor" dc:"游标，首页为空"`
+	Limit  int    `json:"limit" d:"20" v:"between:1,100" dc:"每页数量"`
+}
+
+// KeysetOrder 游标分页排序列，列名由开发者声明，最后一列必须唯一（如主键）以保证顺序稳定
+type KeysetOrder struct {
+	Column string // 排序列，可带表别名，如 o.created_at
+	Desc   bool
+}
+
+// KeysetOptions 游标分页配置
+type KeysetOptions struct {
+	Orders []KeysetOrder
+	Secret []byte // 游标签名密钥，防止客户端篡改游标
+}
+
+type cursorPayload struct {
+	Direction string        `json:"d"`
+	Values    []interface{} `json:"v"`
+}
+
+// KeysetPaginate 游标（keyset）分页，查询 limit+1 行判断是否还有数据，不执行 COUNT，结果扫描到 pointer（切片指针）
+func (rec *model) KeysetPaginate(req CursorReq, opts KeysetOptions, pointer interface{}) (res *response.CursorRes, err error) {
+	if len(opts.Orders) == 0 || len(opts.Secret) == 0 {
+		return nil, errors.New("keyset orders and secret are required")
+	}
+	direction := cursorNext
+	var values []interface{}
+	if req.Cursor != "" {
+		payload, decodeErr := decodeCursor(req.Cursor, opts.Secret)
+		if decodeErr != nil || len(payload.Values) != len(opts.Orders) {
+			return nil, errCursor
+		}
+		direction, values = payload.Direction, payload.Values
+	}
+	m := rec.m.Clone()
+	if values != nil {
+		where, args := keysetWhere(m, opts.Orders, values, direction == cursorPrev)
+		m = m.Where(where, args...)
+	}
+	for _, order := range opts.Orders {
+		if order.Desc == (direction == cursorPrev) { // 向前翻页时反转排序，查询后再反转结果
+			m = m.OrderAsc(order.Column)
+		} else {
+			m = m.OrderDesc(order.Column)
+		}
+	}
+	all, err := m.Limit(req.Limit + 1).All()
+	if err != nil {
+		return
+	}
+	hasMore := len(all) > req.Limit
+	if hasMore {
+		all = all[:req.Limit]
+	}
+	if direction == cursorPrev {
+		for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+			all[i], all[j] = all[j], all[i]
+		}
+	}
+	res = &response.CursorRes{HasMore: hasMore}
+	if len(all) > 0 {
+		if (direction == cursorNext && hasMore) || direction == cursorPrev {
+			res.NextCursor = encodeCursor(cursorNext, keysetValues(all[len(all)-1], opts.Orders), opts.Secret)
+		}
+		if (direction == cursorPrev && hasMore) || (direction == cursorNext && req.Cursor != "") {
+			res.PrevCursor = encodeCursor(cursorPrev, keysetValues(all[0], opts.Orders), opts.Secret)
+		}
+		if err = all.Structs(pointer); err != nil {
+			return nil, err
+		}
+	}
+	items := reflect.ValueOf(pointer).Elem()
+	if items.Kind() == reflect.Slice && items.IsNil() {
+		items.Set(reflect.MakeSlice(items.Type(), 0, 0)) // 输出空数组 []，而非 null
+	}
+	res.Items = items.Interface()
+	return
+}
+
+// keysetWhere 生成 (c1 < v1) OR (c1 = v1 AND c2 < v2) ... 形式的条件，reverse 为向前翻页
+func keysetWhere(m *gdb.Model, orders []KeysetOrder, values []interface{}, reverse bool) (where string, args []interface{}) {
+	var ors []string
+	for i, order := range orders {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, m.QuoteWord(orders[j].Column)+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if order.Desc != reverse {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", m.QuoteWord(order.Column), op))
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// keysetValues 读取边界行的排序列值，带表别名的列按去除别名后的字段名读取
+func keysetValues(record gdb.Record, orders []KeysetOrder) []interface{} {
+	values := make([]interface{}, len(orders))
+	for i, order := range orders {
+		column := order.Column
+		if pos := strings.LastIndex(column, "."); pos >= 0 {
+			column = column[pos+1:]
+		}
+		if v, ok := record[column]; ok && v != nil {
+			values[i] = v.String()
+		}
+	}
+	return values
+}
+
+func encodeCursor(direction string, values []interface{}, secret []byte) string {
+	b, _ := json.Marshal(cursorPayload{Direction: direction, Values: values})
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signCursor(payload, secret))
+}
+
+func decodeCursor(cursor string, secret []byte) (payload cursorPayload, err error) {
+	parts := strings.Split(cursor, ".")
+	if len(parts) != 2 {
+		return payload, errCursor
+	}
+	sign, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sign, signCursor(parts[0], secret)) {
+		return payload, errCursor
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(b, &payload); err != nil {
+		return
+	}
+	if payload.Direction != cursorNext && payload.Direction != cursorPrev {
+		return payload, errCursor
+	}
+	return
+}
+
+func signCursor(payload string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,158 @@
+package helper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// 过滤操作符
+const (
+	FilterEq    = "eq"    // col = v，零值忽略，指针字段非 nil 时生效
+	FilterIn    = "in"    // col IN (v...)，切片或逗号分隔字符串
+	FilterRange = "range" // min <= col <= max，两个元素的切片或逗号分隔字符串，任一端可为空
+	FilterLike  = "like"  // col LIKE %v%，通配符会被转义
+	FilterNull  = "null"  // true 为 IS NULL，false 为 IS NOT NULL，需使用 *bool
+)
+
+// Filter 按 req 结构体标签安全地应用过滤和排序，返回新的 Model 以便继续 Paginate
+// 过滤字段声明 filter:"列名,操作符"，如 Status []string `json:"status" filter:"status,in"`
+// 排序字段声明 sort:"参数名:列名,..." 白名单，如 Sort string `json:"sort" sort:"createdAt:created_at,amount"`，请求值如 -createdAt,amount，- 为倒序
+// 列名只来自标签声明，请求值只作为参数绑定或白名单匹配，不会拼接进 SQL
+func (rec *model) Filter(req interface{}) (res *model, err error) {
+	m := rec.m.Clone()
+	v := reflect.Indirect(reflect.ValueOf(req))
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("filter req must be a struct, got %T", req)
+	}
+	if m, err = applyFilter(m, v); err != nil {
+		return
+	}
+	return &model{m}, nil
+}
+
+func applyFilter(m *gdb.Model, v reflect.Value) (*gdb.Model, error) {
+	var err error
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if field.Anonymous && reflect.Indirect(value).Kind() == reflect.Struct {
+			if m, err = applyFilter(m, reflect.Indirect(value)); err != nil { // 嵌入的结构体，如 PageReq
+				return nil, err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if tag := field.Tag.Get("filter"); tag != "" {
+			if m, err = applyFilterField(m, tag, value); err != nil {
+				return nil, err
+			}
+		}
+		if tag := field.Tag.Get("sort"); tag != "" {
+			if m, err = applySort(m, tag, gconv.String(value.Interface())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+func applyFilterField(m *gdb.Model, tag string, value reflect.Value) (*gdb.Model, error) {
+	parts := strings.Split(tag, ",")
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return nil, fmt.Errorf(`filter tag "%s" must be "column,operator"`, tag)
+	}
+	column, op := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return m, nil
+		}
+		value = value.Elem()
+	} else if value.IsZero() {
+		return m, nil
+	}
+	switch op {
+	case FilterEq:
+		return m.Where(column, value.Interface()), nil
+	case FilterIn:
+		if in := filterValues(value); len(in) > 0 {
+			return m.WhereIn(column, in), nil
+		}
+	case FilterRange:
+		bounds := filterValues(value)
+		if len(bounds) > 2 {
+			return nil, fmt.Errorf("range filter of %s accepts two values", column)
+		}
+		if len(bounds) >= 1 && bounds[0] != "" {
+			m = m.WhereGTE(column, bounds[0])
+		}
+		if len(bounds) == 2 && bounds[1] != "" {
+			m = m.WhereLTE(column, bounds[1])
+		}
+	case FilterLike:
+		if like := gconv.String(value.Interface()); like != "" {
+			return m.WhereLike(column, "%"+escapeLike(like)+"%"), nil
+		}
+	case FilterNull:
+		if value.Kind() != reflect.Bool {
+			return nil, fmt.Errorf("null filter of %s must be a *bool field", column)
+		}
+		if value.Bool() {
+			return m.WhereNull(column), nil
+		}
+		return m.WhereNotNull(column), nil
+	default:
+		return nil, fmt.Errorf(`filter operator "%s" of %s is not supported`, op, column)
+	}
+	return m, nil
+}
+
+// applySort 按白名单应用排序，参数名不在白名单时返回错误
+func applySort(m *gdb.Model, tag string, value string) (*gdb.Model, error) {
+	allowed := make(map[string]string)
+	for _, item := range strings.Split(tag, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), ":", 2)
+		if len(kv) == 2 {
+			allowed[kv[0]] = kv[1]
+		} else {
+			allowed[kv[0]] = kv[0]
+		}
+	}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		desc := strings.HasPrefix(item, "-")
+		column, ok := allowed[strings.TrimPrefix(item, "-")]
+		if !ok {
+			return nil, fmt.Errorf("sort field %s is not allowed", strings.TrimPrefix(item, "-"))
+		}
+		if desc {
+			m = m.OrderDesc(column)
+		} else {
+			m = m.OrderAsc(column)
+		}
+	}
+	return m, nil
+}
+
+// filterValues 读取切片或逗号分隔字符串的值
+func filterValues(value reflect.Value) []string {
+	if value.Kind() == reflect.String {
+		if value.String() == "" {
+			return nil
+		}
+		return strings.Split(value.String(), ",")
+	}
+	return gconv.Strings(value.Interface())
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
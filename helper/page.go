@@ -0,0 +1,37 @@
+package helper
+
+import (
+	"reflect"
+
+	"github.com/fainc/gfe/response"
+)
+
+// PageReq 分页请求参数，嵌入路由 req 使用，字段名与 response.PageRes 的 Link 头一致
+type PageReq struct {
+	Page     int `json:"page" d:"1" v:"min:1" dc:"页码"`
+	PageSize int `json:"pageSize" d:"20" v:"between:1,100" dc:"每页数量"`
+}
+
+// Paginate 分页查询，先 count 再查询当前页并扫描到 pointer（切片指针），返回可直接输出的分页结果
+func (rec *model) Paginate(req PageReq, pointer interface{}) (res *response.PageRes, err error) {
+	rows, pages, err := rec.CountWithPage(req.PageSize)
+	if err != nil {
+		return
+	}
+	if rows > 0 {
+		if err = rec.m.Clone().Page(req.Page, req.PageSize).Scan(pointer); err != nil {
+			return
+		}
+	}
+	items := reflect.ValueOf(pointer).Elem()
+	if items.Kind() == reflect.Slice && items.IsNil() {
+		items.Set(reflect.MakeSlice(items.Type(), 0, 0)) // 输出空数组 []，而非 null
+	}
+	return &response.PageRes{
+		Items:    items.Interface(),
+		Total:    rows,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+		Pages:    pages,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/net/ghttp"
+
+	"github.com/fainc/gfe/crypt"
+	"github.com/fainc/gfe/response"
+	"github.com/fainc/gfe/util"
+)
+
+type decryptor struct {
+	ds map[string]crypt.Decryptor
+}
+
+// Decryptor 请求体解密中间件，按信封 alg 选择解密器，解密后的明文替换请求体再交由框架解析
+// 路由 req 声明 x-decrypt:"true" 时强制要求加密请求，其它路由仅在请求头 X-Encrypted: true 时解密
+func Decryptor(decryptors ...crypt.Decryptor) *decryptor {
+	ds := make(map[string]crypt.Decryptor, len(decryptors))
+	for _, d := range decryptors {
+		ds[d.Alg()] = d
+	}
+	return &decryptor{ds: ds}
+}
+
+func (rec *decryptor) Register(r *ghttp.Request) {
+	required := util.GetReqMetaStr(r, "x-decrypt") == "true"
+	if !required && r.GetHeader("X-Encrypted") != "true" {
+		r.Middleware.Next()
+		return
+	}
+	env := &crypt.Envelope{}
+	if err := gjson.DecodeTo(r.GetBody(), env); err != nil || env.CipherText == "" {
+		r.SetError(response.DecryptionError(r.Context(), "request body is not an encrypted envelope"))
+		return
+	}
+	d, ok := rec.ds[env.Alg]
+	if !ok {
+		r.SetError(response.DecryptionError(r.Context(), "unsupported alg "+env.Alg))
+		return
+	}
+	plain, err := d.Decrypt(env)
+	if err != nil {
+		r.SetError(response.DecryptionError(r.Context(), "request body cannot be decrypted")) // 不返回具体原因，避免泄露解密细节
+		return
+	}
+	r.Request.Body = io.NopCloser(bytes.NewReader(plain))
+	r.Request.ContentLength = int64(len(plain))
+	r.Request.Header.Set("Content-Type", "application/json")
+	r.ReloadParam() // 清除已缓存的请求体，使框架重新解析明文
+	r.Middleware.Next()
+}
@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gredis"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/gogf/gf/v2/util/gconv"
+
+	"github.com/fainc/gfe/helper"
+	"github.com/fainc/gfe/response"
+	"github.com/fainc/gfe/util"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	idempotencyPollInterval   = 50 * time.Millisecond
+	idempotencyDefaultTTL     = 24 * time.Hour
+	idempotencyDefaultLockTTL = time.Minute
+	idempotencyStoreKeyPrefix = "idempotency:"
+)
+
+// IdempotencyRecord 幂等记录，Done 为 false 时表示请求处理中
+type IdempotencyRecord struct {
+	BodyHash string
+	Done     bool
+	Status   int
+	Header   http.Header
+	Body     []byte
+}
+
+// IdempotencyStore 幂等记录存储
+type IdempotencyStore interface {
+	// Reserve 键不存在时写入处理中记录并返回 nil，否则返回已有记录
+	Reserve(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) (existing *IdempotencyRecord, err error)
+	// Save 保存已完成的响应
+	Save(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) error
+	// Release 删除记录，允许相同 key 重新请求
+	Release(ctx context.Context, key string) error
+}
+
+type IdempotencyOptions struct {
+	Store   IdempotencyStore // 默认内存存储，多实例部署请使用 IdempotencyRedisStore
+	TTL     time.Duration    // 已完成响应的回放有效期，默认 24 小时
+	LockTTL time.Duration    // 处理中记录的有效期，进程异常退出时 key 最多锁定该时长，应大于接口最长处理时间，默认 1 分钟
+	Wait    time.Duration    // 相同 key 的请求处理中时的最长等待时间，0 表示直接返回 409
+}
+
+type idempotency struct {
+	opts IdempotencyOptions
+}
+
+// Idempotency 幂等中间件，需注册在 Response 中间件之内、Jwt 中间件之后，未被 Response 中间件包裹时仅拦截并发的重复请求，不保存响应
+// 路由 req 声明 x-idempotent:"true" 时按 Idempotency-Key 请求头（可选）去重，声明 x-idempotent:"required" 时缺少请求头返回错误
+// 幂等 key 按 CtxUser UID 隔离，重复请求回放首次响应的状态码、响应头及响应体，并携带 Idempotent-Replayed: true 响应头
+// 相同 key 请求体不同返回 422，首次请求处理中返回 409（或等待 Wait），5xx、流式及文件等直出响应不保存
+func Idempotency(opts ...IdempotencyOptions) *idempotency {
+	var o IdempotencyOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Store == nil {
+		o.Store = IdempotencyMemoryStore()
+	}
+	if o.TTL <= 0 {
+		o.TTL = idempotencyDefaultTTL
+	}
+	if o.LockTTL <= 0 {
+		o.LockTTL = idempotencyDefaultLockTTL
+	}
+	return &idempotency{opts: o}
+}
+
+func (rec *idempotency) Register(r *ghttp.Request) {
+	var (
+		ctx  = r.Context()
+		mode = util.GetReqMetaStr(r, "x-idempotent")
+		key  = r.GetHeader(HeaderIdempotencyKey)
+	)
+	if mode != "true" && mode != "required" {
+		r.Middleware.Next()
+		return
+	}
+	if key == "" {
+		if mode == "required" {
+			r.SetError(response.StandError(ctx, "IdempotencyKeyRequired"))
+			return
+		}
+		r.Middleware.Next()
+		return
+	}
+	sum := sha256.Sum256(r.GetBody())
+	var (
+		storeKey = idempotencyStoreKeyPrefix + gconv.String(helper.CtxUser().Get(ctx).UID) + ":" + r.Method + r.URL.Path + ":" + key
+		record   = &IdempotencyRecord{BodyHash: hex.EncodeToString(sum[:])}
+		deadline = time.Now().Add(rec.opts.Wait)
+	)
+	for {
+		existing, err := rec.opts.Store.Reserve(ctx, storeKey, record, rec.opts.LockTTL)
+		if err != nil {
+			r.SetError(gerror.WrapCode(gcode.CodeInternalError, err, "idempotency store unavailable"))
+			return
+		}
+		if existing == nil {
+			break
+		}
+		if existing.BodyHash != record.BodyHash {
+			r.SetError(response.IdempotencyKeyReusedError(ctx, key))
+			return
+		}
+		if existing.Done {
+			rec.replay(r, existing)
+			return
+		}
+		if !time.Now().Before(deadline) {
+			r.SetError(response.IdempotencyConflictError(ctx, key))
+			return
+		}
+		select { // 等待首次请求完成或释放
+		case <-ctx.Done():
+			r.SetError(response.IdempotencyConflictError(ctx, key))
+			return
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+	if !response.OnWritten(r, func(r *ghttp.Request) { rec.save(r, storeKey, record.BodyHash) }) {
+		defer rec.release(ctx, storeKey) // 无法获取写入的响应，请求结束后释放 key
+	}
+	r.Middleware.Next()
+}
+
+// save 保存 Response 中间件写入的响应，无法回放的响应释放 key
+func (rec *idempotency) save(r *ghttp.Request, storeKey, bodyHash string) {
+	var (
+		ctx         = r.Context()
+		status      = r.Response.Status
+		contentType = r.Response.Header().Get("Content-Type")
+	)
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if response.Passthrough(r) != "" || status >= http.StatusInternalServerError || status == http.StatusNotModified ||
+		strings.HasPrefix(contentType, "text/event-stream") || strings.HasPrefix(contentType, "application/x-ndjson") {
+		rec.release(ctx, storeKey)
+		return
+	}
+	header := r.Response.Header().Clone()
+	header.Del("Server-Timing")
+	if header.Get("Content-Encoding") != "" {
+		// 保存压缩前的响应，重试请求的 Accept-Encoding 可能不同，回放时不压缩
+		header.Del("Content-Encoding")
+		header.Del("ETag") // ETag 区分压缩编码
+	}
+	done := &IdempotencyRecord{BodyHash: bodyHash, Done: true, Status: status, Header: header, Body: response.WrittenBody(r)}
+	if err := rec.opts.Store.Save(ctx, storeKey, done, rec.opts.TTL); err != nil {
+		g.Log().Warning(ctx, "idempotency save failed: "+err.Error())
+	}
+}
+
+// release 释放 key，失败时 key 在 LockTTL 后过期
+func (rec *idempotency) release(ctx context.Context, storeKey string) {
+	if err := rec.opts.Store.Release(ctx, storeKey); err != nil {
+		g.Log().Warning(ctx, "idempotency release failed: "+err.Error())
+	}
+}
+
+// replay 回放已保存的响应
+func (rec *idempotency) replay(r *ghttp.Request, record *IdempotencyRecord) {
+	header := r.Response.Header()
+	for k, v := range record.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+	r.Response.ClearBuffer()
+	r.Response.WriteStatus(record.Status, record.Body)
+	response.SetDefaultResponseHeader(r)
+	r.ExitAll()
+}
+
+type idempotencyMemoryStore struct {
+	c *gcache.Cache
+}
+
+// IdempotencyMemoryStore 进程内存幂等存储，仅适用于单实例部署
+func IdempotencyMemoryStore() IdempotencyStore {
+	return &idempotencyMemoryStore{c: gcache.New()}
+}
+
+func (s *idempotencyMemoryStore) Reserve(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error) {
+	for {
+		ok, err := s.c.SetIfNotExist(ctx, key, record, ttl)
+		if err != nil || ok {
+			return nil, err
+		}
+		v, err := s.c.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if existing, ok := v.Val().(*IdempotencyRecord); ok {
+			return existing, nil
+		}
+		// 记录已过期，重新占用
+	}
+}
+
+func (s *idempotencyMemoryStore) Save(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) error {
+	return s.c.Set(ctx, key, record, ttl)
+}
+
+func (s *idempotencyMemoryStore) Release(ctx context.Context, key string) error {
+	_, err := s.c.Remove(ctx, key)
+	return err
+}
+
+type idempotencyRedisStore struct {
+	rds *gredis.Redis
+}
+
+// IdempotencyRedisStore 使用配置名称的 Redis 幂等存储
+func IdempotencyRedisStore(name string) IdempotencyStore {
+	return &idempotencyRedisStore{rds: g.Redis(name)}
+}
+
+func (s *idempotencyRedisStore) Reserve(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error) {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	ms := ttl.Milliseconds()
+	for {
+		set, err := s.rds.Set(ctx, key, value, gredis.SetOption{TTLOption: gredis.TTLOption{PX: &ms}, NX: true})
+		if err != nil {
+			return nil, err
+		}
+		if !set.IsNil() {
+			return nil, nil
+		}
+		v, err := s.rds.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if v.IsNil() {
+			continue // 记录已过期，重新占用
+		}
+		existing := &IdempotencyRecord{}
+		if err = json.Unmarshal(v.Bytes(), existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+}
+
+func (s *idempotencyRedisStore) Save(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	ms := ttl.Milliseconds()
+	_, err = s.rds.Set(ctx, key, value, gredis.SetOption{TTLOption: gredis.TTLOption{PX: &ms}})
+	return err
+}
+
+func (s *idempotencyRedisStore) Release(ctx context.Context, key string) error {
+	_, err := s.rds.Del(ctx, key)
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/errors/gcode"
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
@@ -15,6 +16,7 @@ import (
 	"github.com/gogf/gf/v2/util/gconv"
 
 	"github.com/fainc/gfe/helper"
+	"github.com/fainc/gfe/response"
 	"github.com/fainc/gfe/util"
 )
 
@@ -58,7 +60,7 @@ func (rec *logger) Register(r *ghttp.Request) {
 	shouldLog := rec.AccessLog // 是否需要日志
 	if shouldLog {
 		// 移除不需要日志的内容
-		if util.GetReqMetaStr(r, "x-logger-ignore") == "true" || gstr.Contains(r.RequestURI, "api.json") || gstr.Contains(r.RequestURI, "/debug/pprof/") || r.Response.Writer.Header().Get("Content-Type") == "application/force-download" {
+		if util.GetReqMetaStr(r, "x-logger-ignore") == "true" || gstr.Contains(r.RequestURI, "api.json") || gstr.Contains(r.RequestURI, "/debug/pprof/") || response.Passthrough(r) == response.PassthroughFile {
 			shouldLog = false
 		}
 	}
@@ -78,6 +80,12 @@ func (rec *logger) Register(r *ghttp.Request) {
 		if rec.AccessMaxLimit >= 1 && r.Response.BufferLength() > int(rec.AccessMaxLimit) {
 			buffer = "buffer bytes exceed the limit"
 		}
+		if respContent := r.Response.Writer.Header().Get("Content-Type"); gstr.HasPrefix(respContent, "text/event-stream") || gstr.HasPrefix(respContent, "application/x-ndjson") {
+			buffer = "stream response" // 流式响应已逐条写出，缓冲区为空
+		}
+		if encoding := r.Response.Writer.Header().Get("Content-Encoding"); encoding != "" {
+			buffer = encoding + " compressed response" // 压缩后的二进制内容不记录
+		}
 		header := gmap.New()
 		for _, key := range rec.AccessHeaderKey {
 			header.Set(key, r.GetHeader(key))
@@ -106,7 +114,9 @@ func (rec *logger) Exception(ctx context.Context, err error) {
 	code := gerror.Code(err)
 	// -1 未定义错误，一般直接抛出error产生，51，请求参数验证错误
 	if (code.Code() < 400 && code.Code() != -1 && code.Code() != 51) || (code.Code() < 1000 && code.Code() >= 500) { // 需要捕获错误信息的code范围
-		if gerror.HasStack(err) {
+		if code == gcode.CodeInternalPanic { // panic 恢复的错误附带错误ID，与响应 detail 中的 errorId 对应
+			rec.writeError(ctx, "errorId "+response.ErrorID(ctx)+"\n"+err.Error()+"\n"+gerror.Stack(err))
+		} else if gerror.HasStack(err) {
 			rec.writeError(ctx, gerror.Stack(err)+gconv.String(code.Detail()))
 		} else {
 			rec.writeError(ctx, err)
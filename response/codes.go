@@ -0,0 +1,140 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/net/goai"
+)
+
+// Code is an error code declared in the registry.
+type Code struct {
+	Code        int    `json:"code"`
+	MessageKey  string `json:"messageKey"` // MessageKey is translated with gi18n as the error message.
+	HTTPStatus  int    `json:"httpStatus"` // HTTPStatus is used by StatusDeclared and the problem details mode, 0 keeps the default status.
+	Description string `json:"description"`
+}
+
+// CodeDecryptionError is the code of DecryptionError, 420 is unassigned by HTTP so that 403 stays available to the application.
+const CodeDecryptionError = 420
+
+var (
+	codesMu sync.RWMutex
+	codes   = map[int]Code{}
+)
+
+func init() {
+	for _, c := range []Code{
+		{Code: -1, HTTPStatus: http.StatusBadRequest, Description: "Stand error, the message describes the problem"},
+		{Code: gcode.CodeValidationFailed.Code(), HTTPStatus: http.StatusBadRequest, Description: "Request parameters validation failed"},
+		{Code: 401, MessageKey: "Unauthorized", HTTPStatus: http.StatusUnauthorized, Description: "Token is missing, invalid or revoked"},
+		{Code: 402, MessageKey: "SignatureError", HTTPStatus: http.StatusBadRequest, Description: "Request signature is invalid"},
+		{Code: CodeDecryptionError, MessageKey: "DecryptionError", HTTPStatus: http.StatusBadRequest, Description: "Encrypted request body cannot be decrypted, or the client key of the encrypted response is invalid"},
+		{Code: http.StatusInternalServerError, MessageKey: http.StatusText(http.StatusInternalServerError), HTTPStatus: http.StatusInternalServerError, Description: "Internal server error, details are logged"},
+	} {
+		codes[c.Code] = c
+	}
+}
+
+// RegisterCode declares an error code once, a duplicate code returns an error.
+// The code must be -1, 51, in the range 400 to 499 or >=1000, which are the codes written by the responder.
+func RegisterCode(c Code) error {
+	if !(c.Code == -1 || c.Code == gcode.CodeValidationFailed.Code() || c.Code >= 400 && c.Code < 500 || c.Code >= 1000) {
+		return fmt.Errorf("error code %d is out of the responder ranges", c.Code)
+	}
+	codesMu.Lock()
+	defer codesMu.Unlock()
+	if old, ok := codes[c.Code]; ok {
+		return fmt.Errorf("error code %d is already registered: %s", c.Code, old.Description)
+	}
+	codes[c.Code] = c
+	return nil
+}
+
+// MustRegisterCode declares error codes and panics on duplicates, it is used at startup, e.g. in package init functions.
+func MustRegisterCode(cs ...Code) {
+	for _, c := range cs {
+		if err := RegisterCode(c); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// LookupCode returns the declared error code.
+func LookupCode(code int) (c Code, ok bool) {
+	codesMu.RLock()
+	defer codesMu.RUnlock()
+	c, ok = codes[code]
+	return
+}
+
+// DeclaredError returns an error of a declared code with the i18n message of the MessageKey.
+// Undeclared codes return a 500 InternalError, so that the mistake is logged instead of reaching the client.
+func DeclaredError(ctx context.Context, code int, detail ...interface{}) error {
+	c, ok := LookupCode(code)
+	if !ok {
+		return InternalError(fmt.Sprintf("Undeclared Error Code %v", code))
+	}
+	return CodeErrorTranslate(ctx, c.Code, c.MessageKey, detail...)
+}
+
+// DeclaredErrorFormat returns an error of a declared code with the i18n format message of the MessageKey.
+func DeclaredErrorFormat(ctx context.Context, code int, values ...interface{}) error {
+	c, ok := LookupCode(code)
+	if !ok {
+		return InternalError(fmt.Sprintf("Undeclared Error Code %v", code))
+	}
+	return CodeErrorTranslateFormat(ctx, c.Code, c.MessageKey, values...)
+}
+
+// Codes returns the declared error codes sorted by code.
+func Codes() []Code {
+	codesMu.RLock()
+	defer codesMu.RUnlock()
+	list := make([]Code, 0, len(codes))
+	for _, c := range codes {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+// CodesJSON exports the declared error codes as JSON.
+func CodesJSON() ([]byte, error) {
+	return json.MarshalIndent(Codes(), "", "  ")
+}
+
+// CodesMarkdown exports the declared error codes as a Markdown table.
+func CodesMarkdown() string {
+	var b strings.Builder
+	b.WriteString("| Code | HTTP Status | Message Key | Description |\n")
+	b.WriteString("|-----:|------------:|:------------|:------------|\n")
+	for _, c := range Codes() {
+		b.WriteString(fmt.Sprintf("| %d | %d | %s | %s |\n", c.Code, c.HTTPStatus, c.MessageKey, c.Description))
+	}
+	return b.String()
+}
+
+// MergeCodesIntoOpenApi adds the "ErrorCode" schema which enumerates the declared codes, e.g. MergeCodesIntoOpenApi(s.GetOpenApi()).
+func MergeCodesIntoOpenApi(oai *goai.OpenApiV3) {
+	var enum []interface{}
+	for _, c := range Codes() {
+		enum = append(enum, c.Code)
+	}
+	oai.Components.Schemas.Set("ErrorCode", goai.SchemaRef{
+		Value: &goai.Schema{
+			Type:        goai.TypeInteger,
+			Title:       "ErrorCode",
+			Description: CodesMarkdown(),
+			Enum:        enum,
+		},
+	})
+}
@@ -0,0 +1,130 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/gconv"
+
+	"github.com/fainc/gfe/util"
+)
+
+const defaultCompressMinSize = 1024
+
+// encodingPreference is the server preference of the content codings when the client accepts them with equal q-values.
+var encodingPreference = []string{"br", "gzip", "deflate"}
+
+var defaultCompressTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/yaml",
+	"application/x-msgpack",
+	"application/cbor",
+	"application/x-protobuf",
+	"text/",
+}
+
+// CompressOptions enables gzip, deflate and brotli compression negotiated from the Accept-Encoding header.
+// Routes declaring the x-compress-ignore:"true" meta tag, e.g. downloads of already compressed files, are not compressed.
+type CompressOptions struct {
+	MinSize      int      // MinSize is the minimum body size to compress, defaults to 1024 bytes.
+	ContentTypes []string // ContentTypes is the allowlist of Content-Type prefixes, defaults to the text based and registered formats.
+}
+
+// contentEncoding returns the negotiated content coding of the body, or an empty string if it should not be compressed.
+func (rec *responder) contentEncoding(r *ghttp.Request, body []byte, contentType string) string {
+	opts := rec.opts.Compress
+	if opts == nil || r.Response.Header().Get("Content-Encoding") != "" || util.GetReqMetaStr(r, "x-compress-ignore") == "true" {
+		return ""
+	}
+	types := opts.ContentTypes
+	if len(types) == 0 {
+		types = defaultCompressTypes
+	}
+	allowed := false
+	for _, t := range types {
+		if strings.HasPrefix(contentType, t) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return ""
+	}
+	r.Response.Header().Add("Vary", "Accept-Encoding") // the response varies with Accept-Encoding even when it is small.
+	minSize := opts.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressMinSize
+	}
+	if len(body) < minSize {
+		return ""
+	}
+	return negotiateEncoding(r.GetHeader("Accept-Encoding"))
+}
+
+// negotiateEncoding returns the accepted content coding with the highest q-value, ties are broken by encodingPreference.
+func negotiateEncoding(acceptEncoding string) (encoding string) {
+	if acceptEncoding == "" {
+		return ""
+	}
+	q := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		value := 1.0
+		for _, param := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				value = gconv.Float64(strings.TrimSpace(kv[1]))
+			}
+		}
+		if name == "*" {
+			wildcard = value
+		} else {
+			q[name] = value
+		}
+	}
+	best := 0.0
+	for _, name := range encodingPreference {
+		value, ok := q[name]
+		if !ok {
+			value = wildcard
+		}
+		if value > best {
+			best, encoding = value, name
+		}
+	}
+	return
+}
+
+// compressBody compresses the body with the content coding and sets the Content-Encoding header.
+func compressBody(r *ghttp.Request, body []byte, encoding string) []byte {
+	var (
+		buf    bytes.Buffer
+		writer io.WriteCloser
+	)
+	switch encoding {
+	case "br":
+		writer = brotli.NewWriter(&buf)
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		writer = zlib.NewWriter(&buf) // the "deflate" coding of HTTP is the zlib format.
+	default:
+		return body
+	}
+	if _, err := writer.Write(body); err != nil {
+		return body
+	}
+	if err := writer.Close(); err != nil {
+		return body
+	}
+	r.Response.Header().Set("Content-Encoding", encoding)
+	return buf.Bytes()
+}
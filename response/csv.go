@@ -0,0 +1,149 @@
+package response
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/gconv"
+
+	"github.com/fainc/gfe/util"
+)
+
+// csvEncoder writes list payloads as CSV with a UTF-8 BOM, so that spreadsheets detect the encoding.
+// The payload must be a slice of structs or maps, a PageRes, or a struct whose first slice field holds the rows.
+// Struct columns are named by their json tags. Cells which spreadsheets would evaluate as formulas are prefixed with a quote.
+type csvEncoder struct{}
+
+func (csvEncoder) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (csvEncoder) Raw() bool { return true }
+
+func (csvEncoder) Encode(v interface{}) ([]byte, error) {
+	rows, err := csvRows(v)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		buf    = bytes.NewBufferString("\xEF\xBB\xBF")
+		writer = csv.NewWriter(buf)
+		header []string
+		fields []int
+	)
+	for i := 0; i < rows.Len(); i++ {
+		row := reflect.Indirect(rows.Index(i))
+		if row.Kind() == reflect.Interface {
+			row = reflect.Indirect(row.Elem())
+		}
+		if i == 0 {
+			header, fields = csvHeader(row)
+			if err = writer.Write(csvCells(header)); err != nil {
+				return nil, err
+			}
+		}
+		record := make([]string, len(header))
+		if row.Kind() == reflect.Struct {
+			for j := range header {
+				record[j] = gconv.String(row.Field(fields[j]).Interface())
+			}
+		} else {
+			m := gconv.Map(row.Interface())
+			for j, name := range header {
+				record[j] = gconv.String(m[name])
+			}
+		}
+		if err = writer.Write(csvCells(record)); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// csvCells escapes the cells starting with =, +, -, @, tab or carriage return against formula injection, numbers such as -12.5 are kept.
+func csvCells(record []string) []string {
+	for i, cell := range record {
+		if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			continue
+		}
+		if _, err := strconv.ParseFloat(cell, 64); err != nil {
+			record[i] = "'" + cell
+		}
+	}
+	return record
+}
+
+// setCSVAttachment writes the Content-Disposition of a csv download, named by the filename meta tag or the last path segment.
+func setCSVAttachment(r *ghttp.Request) {
+	name := util.GetReqMetaStr(r, MetaFilename)
+	if name == "" {
+		name = path.Base(r.URL.Path)
+		if name == "/" || name == "." {
+			name = "export"
+		}
+		name += ".csv"
+	}
+	r.Response.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	r.Response.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
+}
+
+// csvRows returns the slice value holding the rows of the payload.
+func csvRows(v interface{}) (reflect.Value, error) {
+	if p, ok := v.(pager); ok && p.page() != nil {
+		v = p.page().Items
+	}
+	rows := reflect.Indirect(reflect.ValueOf(v))
+	if rows.Kind() == reflect.Struct {
+		for i := 0; i < rows.NumField(); i++ {
+			field := rows.Field(i)
+			for (field.Kind() == reflect.Interface || field.Kind() == reflect.Ptr) && !field.IsNil() {
+				field = field.Elem()
+			}
+			if !rows.Type().Field(i).IsExported() {
+				continue
+			}
+			if field.Kind() == reflect.Slice {
+				return field, nil
+			}
+			if rows.Type().Field(i).Anonymous && field.Kind() == reflect.Struct {
+				return csvRows(field.Interface()) // e.g. the PageRes embedded in a masked payload.
+			}
+		}
+	}
+	if rows.Kind() != reflect.Slice && rows.Kind() != reflect.Array {
+		return rows, fmt.Errorf("csv payload must be a list, got %T", v)
+	}
+	return rows, nil
+}
+
+// csvHeader returns the column names of a row and, for structs, the matching field indexes.
+func csvHeader(row reflect.Value) (header []string, fields []int) {
+	switch row.Kind() {
+	case reflect.Struct:
+		for i := 0; i < row.NumField(); i++ {
+			field := row.Type().Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			header = append(header, name)
+			fields = append(fields, i)
+		}
+	default:
+		for key := range gconv.Map(row.Interface()) {
+			header = append(header, key)
+		}
+		sort.Strings(header)
+	}
+	return
+}
@@ -0,0 +1,61 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// DetailPolicy controls which error internals the responder exposes to clients.
+type DetailPolicy struct {
+	Detail   bool // Detail writes the code detail of the expected error codes, validation fields are always written.
+	Internal bool // Internal writes the message and detail of unexpected error codes and panics instead of a generic InternalError.
+	Chain    bool // Chain writes the messages of the wrapped error chain as error.chain.
+	Stack    bool // Stack writes the error stack as error.stack.
+}
+
+var (
+	// ProductionDetail exposes the code detail of expected errors only, it is the default policy.
+	ProductionDetail = DetailPolicy{Detail: true}
+	// DevelopmentDetail exposes everything, never use it in production.
+	DevelopmentDetail = DetailPolicy{Detail: true, Internal: true, Chain: true, Stack: true}
+)
+
+// Redactor rewrites the error detail before it is written, e.g. to remove secrets of wrapped driver errors.
+type Redactor func(code int, detail interface{}) interface{}
+
+// detailPolicy returns the policy of the responder, Options.Development selects DevelopmentDetail if DetailPolicy is nil.
+func (rec *responder) detailPolicy() DetailPolicy {
+	switch {
+	case rec.opts.DetailPolicy != nil:
+		return *rec.opts.DetailPolicy
+	case rec.opts.Development:
+		return DevelopmentDetail
+	}
+	return ProductionDetail
+}
+
+// unexpectedError converts an error with an unexpected code into an InternalError, keeping the original error if the policy allows it.
+func (rec *responder) unexpectedError(err error, detail ...interface{}) error {
+	code := gerror.Code(err)
+	if len(detail) == 0 {
+		detail = []interface{}{fmt.Sprintf("Unexpected Error Code %v", code.Code())}
+	}
+	if !rec.detailPolicy().Internal {
+		return InternalError(detail...)
+	}
+	if code.Detail() != nil {
+		detail = append(detail, code.Detail())
+	}
+	return gerror.WrapCode(gcode.New(500, "Internal Server Error", detail), err)
+}
+
+// errorChain returns the messages of the wrapped errors, starting with the cause of err.
+func errorChain(err error) (chain []string) {
+	for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+		chain = append(chain, cause.Error())
+	}
+	return
+}
@@ -0,0 +1,164 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoder serializes the result envelope for an output format.
+type Encoder interface {
+	ContentType() string
+	Encode(v interface{}) ([]byte, error)
+}
+
+// RawEncoder is an Encoder which serializes the success payload without the result envelope, e.g. CSV.
+// Errors and encrypted payloads are written with the JSON encoder instead.
+type RawEncoder interface {
+	Encoder
+	Raw() bool
+}
+
+type encoderItem struct {
+	encoder Encoder
+	mimes   []string
+}
+
+var (
+	encoderMu sync.RWMutex
+	encoders  = map[string]encoderItem{}
+	mimeIndex = map[string]string{}
+)
+
+func init() {
+	RegisterEncoder(FormatJSON, jsonEncoder{}, "application/json", "text/json")
+	RegisterEncoder(FormatXML, xmlEncoder{}, "application/xml", "text/xml")
+	RegisterEncoder(FormatMsgPack, msgPackEncoder{}, "application/x-msgpack", "application/msgpack", "application/vnd.msgpack")
+	RegisterEncoder(FormatYAML, yamlEncoder{}, "application/yaml", "application/x-yaml", "text/yaml")
+	RegisterEncoder(FormatCBOR, cborEncoder{}, "application/cbor")
+	RegisterEncoder(FormatProtobuf, protobufEncoder{}, "application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf")
+	RegisterEncoder(FormatCSV, csvEncoder{}, "text/csv")
+	RegisterEncoder(FormatHTML, htmlEncoder{}, "text/html", "application/xhtml+xml")
+}
+
+// RegisterEncoder registers or replaces the Encoder of a format.
+// The mimes are the media types matched against the Accept header when Options.Negotiate is enabled.
+func RegisterEncoder(format string, encoder Encoder, mimes ...string) {
+	encoderMu.Lock()
+	defer encoderMu.Unlock()
+	if old, ok := encoders[format]; ok {
+		for _, mime := range old.mimes {
+			delete(mimeIndex, mime)
+		}
+	}
+	encoders[format] = encoderItem{encoder: encoder, mimes: mimes}
+	for _, mime := range mimes {
+		mimeIndex[mime] = format
+	}
+}
+
+// getEncoder returns the Encoder of a format, or the JSON encoder if the format is not registered.
+func getEncoder(format string) Encoder {
+	encoderMu.RLock()
+	defer encoderMu.RUnlock()
+	if item, ok := encoders[format]; ok {
+		return item.encoder
+	}
+	return encoders[FormatJSON].encoder
+}
+
+// formatOfMime returns the registered format of a media type.
+func formatOfMime(mime string) string {
+	encoderMu.RLock()
+	defer encoderMu.RUnlock()
+	return mimeIndex[mime]
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string { return "application/json; charset=utf-8" }
+
+func (jsonEncoder) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+type xmlEncoder struct{}
+
+// ContentType overwrites the default XML content type "text/xml" in GF with "application/xml".
+func (xmlEncoder) ContentType() string { return "application/xml; charset=utf-8" }
+
+func (xmlEncoder) Encode(v interface{}) ([]byte, error) {
+	j, err := loadJson(v)
+	if err != nil {
+		return nil, err
+	}
+	return j.ToXml("xml")
+}
+
+type msgPackEncoder struct{}
+
+func (msgPackEncoder) ContentType() string { return "application/x-msgpack" }
+
+func (msgPackEncoder) Encode(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+type yamlEncoder struct{}
+
+func (yamlEncoder) ContentType() string { return "application/yaml; charset=utf-8" }
+
+func (yamlEncoder) Encode(v interface{}) ([]byte, error) {
+	j, err := loadJson(v)
+	if err != nil {
+		return nil, err
+	}
+	return j.ToYaml()
+}
+
+type cborEncoder struct{}
+
+func (cborEncoder) ContentType() string { return "application/cbor" }
+
+func (cborEncoder) Encode(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v) // cbor falls back to the json tags of the envelope.
+}
+
+// loadJson converts v through its JSON form, so that the json tags and embedded fields of the envelope are kept by gjson.
+func loadJson(v interface{}) (*gjson.Json, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var value interface{}
+	if err = decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return gjson.New(restoreNumber(value)), nil
+}
+
+// restoreNumber converts json.Number values back into int64 or float64, so that YAML keeps them as numbers.
+func restoreNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = restoreNumber(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = restoreNumber(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,55 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+
+	"github.com/fainc/gfe/crypt"
+)
+
+// Encryptor encrypts the success payload before it is written, and reports whether the payload was encrypted.
+// An error is written instead of the payload, errors with an unexpected code are written as InternalError.
+type Encryptor func(ctx context.Context, payload interface{}) (result interface{}, encrypted bool, err error)
+
+// SealEncryptor returns an Encryptor which serializes the payload to JSON and seals it with a shared key,
+// e.g. crypt.NewAESGCM or crypt.NewSM4. The result is a crypt.Envelope with nonce and key id.
+func SealEncryptor(s crypt.Sealer) Encryptor {
+	return func(ctx context.Context, payload interface{}) (interface{}, bool, error) {
+		return seal(payload, s.Seal)
+	}
+}
+
+// ECIESEncryptor returns an Encryptor which encrypts the payload to the P-256 public key supplied in the X-Client-Public-Key header.
+// Requests without the header receive a plain payload, a malformed header is answered with DecryptionError.
+// Clients decrypt with crypt.NewECDHDecryptor(clientPrivateKey, "").
+func ECIESEncryptor() Encryptor {
+	return func(ctx context.Context, payload interface{}) (interface{}, bool, error) {
+		r := g.RequestFromCtx(ctx)
+		if r == nil || r.GetHeader(crypt.HeaderClientPublicKey) == "" {
+			return payload, false, nil
+		}
+		pub, err := crypt.ParseClientPublicKey(r.GetHeader(crypt.HeaderClientPublicKey))
+		if err != nil {
+			return nil, false, DecryptionError(ctx, "invalid client public key")
+		}
+		return seal(payload, func(plain []byte) (*crypt.Envelope, error) {
+			return crypt.SealECDH(pub, "", plain)
+		})
+	}
+}
+
+// seal serializes the payload and seals it, an error never falls back to a plain payload once encryption is expected.
+func seal(payload interface{}, sealFunc func(plain []byte) (*crypt.Envelope, error)) (interface{}, bool, error) {
+	plain, err := json.Marshal(payload)
+	if err == nil {
+		var env *crypt.Envelope
+		if env, err = sealFunc(plain); err == nil {
+			return env, true, nil
+		}
+	}
+	return nil, false, gerror.WrapCode(gcode.CodeInternalError, err, "payload cannot be sealed")
+}
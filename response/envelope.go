@@ -0,0 +1,64 @@
+package response
+
+// Result is the outcome of a request passed to the EnvelopeBuilder.
+type Result struct {
+	Ok        bool
+	Payload   interface{} // Payload is the success payload, or the crypt.Envelope if Encrypted.
+	Error     *ResultError
+	Encrypted bool
+}
+
+// ResultError is the error of a failed Result.
+type ResultError struct {
+	Code    int
+	Message string
+	Detail  interface{}
+	Chain   []string // Chain and Stack are set by the DetailPolicy only.
+	Stack   string
+}
+
+// EnvelopeBuilder builds the value written by the encoders of every format from the result, e.g. DefaultEnvelope or CodeMsgDataEnvelope.
+// Protobuf writes its own envelope message for DefaultEnvelope, other builders must return a proto.Message or a type registered with RegisterProtoMapping.
+type EnvelopeBuilder func(result Result) interface{}
+
+// DefaultEnvelope builds {ok,payload,error{code,message,detail},encrypted}.
+func DefaultEnvelope(result Result) interface{} {
+	r := &resultFormat{Ok: result.Ok, Encrypted: result.Encrypted}
+	if result.Error != nil {
+		e := result.Error
+		r.Error = &errorFormat{Code: e.Code, Message: e.Message, Detail: e.Detail, Chain: e.Chain, Stack: e.Stack}
+	} else {
+		r.successFormat = &successFormat{Payload: result.Payload}
+	}
+	return r
+}
+
+type codeMsgDataFormat struct {
+	Code      int         `json:"code" xml:"code" msgpack:"code"`
+	Msg       string      `json:"msg" xml:"msg" msgpack:"msg"`
+	Data      interface{} `json:"data" xml:"data" msgpack:"data"`
+	Encrypted bool        `json:"encrypted,omitempty" xml:"encrypted,omitempty" msgpack:"encrypted,omitempty"`
+}
+
+// CodeMsgDataEnvelope builds {code,msg,data}, success is code 0 with msg "ok" and errors carry their detail as data.
+func CodeMsgDataEnvelope(result Result) interface{} {
+	if result.Error != nil {
+		return &codeMsgDataFormat{Code: result.Error.Code, Msg: result.Error.Message, Data: result.Error.Detail}
+	}
+	return &codeMsgDataFormat{Msg: "ok", Data: result.Payload, Encrypted: result.Encrypted}
+}
+
+// envelope returns the value encoded for the result.
+func (rec *responder) envelope(result *resultFormat) interface{} {
+	if rec.opts.Envelope == nil {
+		return result
+	}
+	r := Result{Ok: result.Ok, Encrypted: result.Encrypted}
+	if result.successFormat != nil {
+		r.Payload = result.Payload
+	}
+	if e := result.Error; e != nil {
+		r.Error = &ResultError{Code: e.Code, Message: e.Message, Detail: e.Detail, Chain: e.Chain, Stack: e.Stack}
+	}
+	return rec.opts.Envelope(r)
+}
@@ -0,0 +1,64 @@
+package response
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+
+	"github.com/fainc/gfe/util"
+)
+
+// SetLastModified sets the Last-Modified header in a handler, the responder answers If-Modified-Since with 304 when Options.ETag is enabled.
+func SetLastModified(ctx context.Context, t time.Time) {
+	if r := g.RequestFromCtx(ctx); r != nil {
+		r.Response.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// setCacheControl writes the Cache-Control header of a successful response from the route meta tag.
+func setCacheControl(r *ghttp.Request) {
+	if cacheControl := util.GetReqMetaStr(r, MetaCacheControl); cacheControl != "" {
+		r.Response.Header().Set("Cache-Control", cacheControl)
+	}
+}
+
+// conditional sets the ETag of a successful GET or HEAD response and reports whether the client copy is still fresh.
+// The strong ETag is computed over the serialized body before compression and suffixed with the content coding.
+func (rec *responder) conditional(r *ghttp.Request, body []byte, encoding string) (notModified bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	sum := sha256.Sum256(body)
+	tag := base64.RawURLEncoding.EncodeToString(sum[:16])
+	if encoding != "" {
+		tag += "-" + encoding // a compressed representation needs its own strong ETag.
+	}
+	etag := `"` + tag + `"`
+	r.Response.Header().Set("ETag", etag)
+	if ifNoneMatch := r.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		return etagMatch(ifNoneMatch, etag) // If-Modified-Since is ignored when If-None-Match is present, see RFC 9110.
+	}
+	ifModifiedSince, err := http.ParseTime(r.GetHeader("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(r.Response.Header().Get("Last-Modified"))
+	return err == nil && !lastModified.After(ifModifiedSince)
+}
+
+// etagMatch checks If-None-Match with the weak comparison of RFC 9110.
+func etagMatch(ifNoneMatch string, etag string) bool {
+	for _, item := range strings.Split(ifNoneMatch, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.TrimPrefix(item, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gview"
+
+	"github.com/fainc/gfe/util"
+)
+
+// MetaTemplate is the meta tag naming the gview template which renders the success payload of a route in the html format,
+// e.g. g.Meta `path:"/admin/users" method:"get" template:"admin/users.html"`. Routes without it are written as JSON.
+const MetaTemplate = "template"
+
+// HTMLOptions configures the html format.
+// Templates are parsed with the request context, so the gview i18n tags, e.g. {#UserList}, follow the request language.
+// The template variables are the result envelope: .ok, .payload, .error{Code, Message, Detail}, .status, the written http status,
+// and .errorStatus, the status of the error itself which differs from .status under StatusAlways200.
+// Negotiate offers the html format only when HTMLOptions is set.
+type HTMLOptions struct {
+	View           *gview.View    // View defaults to g.View().
+	ErrorTemplates map[int]string // ErrorTemplates maps error codes, then error status codes, to error page templates.
+	ErrorTemplate  string         // ErrorTemplate is the fallback error page template, a built-in page is rendered if empty.
+}
+
+const defaultErrorPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.errorStatus}} {{.errorStatusText}}</title></head>
+<body>
+<h1>{{.errorStatusText}}</h1>
+<p>{{.error.Message}}</p>
+<p><small>{{.error.Code}}</small></p>
+</body>
+</html>
+`
+
+// htmlEncoder renders the result envelope of a request through gview templates.
+type htmlEncoder struct {
+	rec        *responder
+	r          *ghttp.Request
+	statusCode int
+}
+
+func (htmlEncoder) ContentType() string { return "text/html; charset=utf-8" }
+
+func (e htmlEncoder) Encode(v interface{}) ([]byte, error) {
+	if e.r == nil {
+		return nil, errors.New("html format is rendered by the responder")
+	}
+	result, ok := v.(*resultFormat)
+	if !ok {
+		result = &resultFormat{Ok: true, successFormat: &successFormat{Payload: v}}
+	}
+	params := g.Map{"ok": result.Ok, "error": result.Error, "encrypted": result.Encrypted, "status": e.statusCode, "statusText": http.StatusText(e.statusCode)}
+	if result.successFormat != nil {
+		params["payload"] = result.Payload
+	}
+	errorStatus := e.statusCode
+	if !result.Ok {
+		_, errorStatus = problemType(result.Error.Code) // the written status is 200 under StatusAlways200.
+		params["errorStatus"], params["errorStatusText"] = errorStatus, http.StatusText(errorStatus)
+	}
+	var (
+		opts     = e.rec.opts.HTML
+		view     = g.View()
+		template string
+	)
+	if opts == nil {
+		opts = &HTMLOptions{}
+	}
+	if opts.View != nil {
+		view = opts.View
+	}
+	if result.Ok {
+		template = util.GetReqMetaStr(e.r, MetaTemplate)
+	} else if template, ok = opts.ErrorTemplates[result.Error.Code]; !ok {
+		if template, ok = opts.ErrorTemplates[errorStatus]; !ok {
+			template = opts.ErrorTemplate
+		}
+	}
+	var (
+		html string
+		err  error
+	)
+	if template == "" {
+		html, err = view.ParseContent(e.r.Context(), defaultErrorPage, params)
+	} else {
+		html, err = view.Parse(e.r.Context(), template, params)
+	}
+	return []byte(html), err
+}
+
+// htmlEncoder returns the html Encoder of the request, success payloads of routes without the template meta tag are written as JSON.
+func (rec *responder) htmlEncoder(r *ghttp.Request, statusCode int, result *resultFormat) Encoder {
+	if result.Ok && util.GetReqMetaStr(r, MetaTemplate) == "" {
+		return getEncoder(FormatJSON)
+	}
+	return htmlEncoder{rec: rec, r: r, statusCode: statusCode}
+}
@@ -0,0 +1,438 @@
+package response
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// FieldsQueryKey is the query parameter of the sparse field projection, e.g. ?fields=id,name,profile.avatar.
+const FieldsQueryKey = "fields"
+
+// MaskFunc masks a field value written with the mask:"name" struct tag.
+type MaskFunc func(value string) string
+
+var masks sync.Map // name => MaskFunc
+
+func init() {
+	RegisterMask("phone", func(value string) string { return maskMiddle(value, 3, 4) })
+	RegisterMask("idCard", func(value string) string { return maskMiddle(value, 6, 4) })
+	RegisterMask("bankCard", func(value string) string { return maskMiddle(value, 4, 4) })
+	RegisterMask("email", func(value string) string {
+		at := strings.LastIndex(value, "@")
+		if at <= 0 {
+			return maskMiddle(value, 1, 0)
+		}
+		return maskMiddle(value[:at], 1, 0) + value[at:]
+	})
+}
+
+// RegisterMask registers or replaces the mask of the mask:"name" struct tag.
+// The built-in masks are phone, email, idCard and bankCard, unknown or empty masks hide the whole value.
+func RegisterMask(name string, mask MaskFunc) {
+	masks.Store(name, mask)
+}
+
+// maskMiddle keeps the head and tail runes of the value and replaces the others with "*".
+func maskMiddle(value string, head, tail int) string {
+	runes := []rune(value)
+	if len(runes) == 0 {
+		return value
+	}
+	if len(runes) <= head+tail {
+		head, tail = 1, 0
+		if len(runes) == 1 {
+			head = 0
+		}
+	}
+	return string(runes[:head]) + strings.Repeat("*", len(runes)-head-tail) + string(runes[len(runes)-tail:])
+}
+
+func maskValue(name string, v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	value := gconv.String(v.Interface())
+	if value == "" {
+		return ""
+	}
+	if mask, ok := masks.Load(name); ok {
+		return mask.(MaskFunc)(value)
+	}
+	return strings.Repeat("*", len([]rune(value)))
+}
+
+// fieldNode is the projection tree of the fields query, a nil node keeps every field.
+type fieldNode map[string]fieldNode
+
+func parseFields(fields string) (root fieldNode) {
+	for _, path := range strings.Split(fields, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if root == nil {
+			root = fieldNode{}
+		}
+		node := root
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			child, ok := node[name]
+			if ok && child == nil {
+				break // the parent field is already kept as a whole.
+			}
+			if i == len(names)-1 {
+				node[name] = nil
+				break
+			}
+			if child == nil {
+				child = fieldNode{}
+				node[name] = child
+			}
+			node = child
+		}
+	}
+	return
+}
+
+func (n fieldNode) String() string {
+	if n == nil {
+		return ""
+	}
+	names := make([]string, 0, len(n))
+	for name, child := range n {
+		if child != nil {
+			name += "{" + child.String() + "}"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// projection is the caller scope of the masks, the visible:"role:..." tags and the fields query.
+type projection struct {
+	roles map[string]bool
+	key   string
+}
+
+type planKey struct {
+	typ    reflect.Type
+	roles  string
+	fields string
+	clone  bool
+}
+
+// plan rewrites values of a type, the destination type is built with reflect.StructOf when fields are dropped or masked.
+type plan struct {
+	typ      reflect.Type // typ is the destination type.
+	kind     reflect.Kind
+	same     bool // same reports whether the destination type is the source type.
+	identity bool // identity reports whether values are never rewritten.
+	elem     *plan
+	fields   []fieldPlan
+	scope    *projection
+	node     fieldNode
+}
+
+type fieldPlan struct {
+	index int
+	mask  *string // mask is the mask name of masked fields.
+	plan  *plan
+}
+
+var (
+	plans           sync.Map // planKey => *plan
+	jsonMarshaler   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	listTypes       = map[reflect.Type]bool{reflect.TypeOf(PageRes{}): true, reflect.TypeOf(CursorRes{}): true}
+	identityPlan    = &plan{same: true, identity: true}
+	maskedFieldType = reflect.TypeOf("")
+)
+
+// project applies the mask and visible struct tags and the fields query to the payload before it is serialized.
+func (rec *responder) project(r *ghttp.Request, payload interface{}) interface{} {
+	if payload == nil {
+		return nil
+	}
+	scope := &projection{roles: map[string]bool{}}
+	if rec.opts.Roles != nil {
+		roles := append([]string(nil), rec.opts.Roles(r.Context())...)
+		sort.Strings(roles)
+		for _, role := range roles {
+			scope.roles[role] = true
+		}
+		scope.key = strings.Join(roles, ",")
+	}
+	var node fieldNode
+	if rec.opts.Projection {
+		node = parseFields(r.GetQuery(FieldsQueryKey).String())
+	}
+	v := reflect.ValueOf(payload)
+	if out, changed := planOf(v.Type(), scope, node, false, nil).apply(v); changed {
+		return out.Interface()
+	}
+	return payload
+}
+
+// planOf returns the cached plan of the type, building is the set of struct types being built which are left untouched when recursive.
+// A clone plan always rebuilds the struct, reflect.StructOf cannot embed types with methods.
+func planOf(t reflect.Type, scope *projection, node fieldNode, clone bool, building map[reflect.Type]bool) *plan {
+	key := planKey{t, scope.key, node.String(), clone}
+	if p, ok := plans.Load(key); ok {
+		return p.(*plan)
+	}
+	if building[t] {
+		return identityPlan
+	}
+	p := buildPlan(t, scope, node, clone, building)
+	// Plans of the fields query are not cached as the query is arbitrary client input,
+	// nested plans are not cached as they may be cut by a recursive type.
+	if key.fields == "" && len(building) == 0 {
+		plans.Store(key, p)
+	}
+	return p
+}
+
+func buildPlan(t reflect.Type, scope *projection, node fieldNode, clone bool, building map[reflect.Type]bool) *plan {
+	if t.Implements(jsonMarshaler) || t.Implements(textMarshaler) || t.Kind() != reflect.Ptr && (reflect.PtrTo(t).Implements(jsonMarshaler) || reflect.PtrTo(t).Implements(textMarshaler)) {
+		return identityPlan
+	}
+	p := &plan{typ: t, kind: t.Kind(), same: true, identity: true, scope: scope, node: node}
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			p.identity = false // the dynamic value is planned when written.
+		}
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		p.elem = planOf(t.Elem(), scope, node, clone && t.Kind() == reflect.Ptr, building)
+		p.same, p.identity = p.elem.same, p.elem.identity
+		if !p.same {
+			switch t.Kind() {
+			case reflect.Ptr:
+				p.typ = reflect.PtrTo(p.elem.typ)
+			case reflect.Slice:
+				p.typ = reflect.SliceOf(p.elem.typ)
+			case reflect.Array:
+				p.typ = reflect.ArrayOf(t.Len(), p.elem.typ)
+			case reflect.Map:
+				p.typ = reflect.MapOf(t.Key(), p.elem.typ)
+			}
+		}
+	case reflect.Struct:
+		if building == nil {
+			building = map[reflect.Type]bool{}
+		}
+		building[t] = true
+		defer delete(building, t)
+		p.buildStruct(t, clone, building)
+	}
+	if p.identity {
+		return identityPlan
+	}
+	return p
+}
+
+func (p *plan) buildStruct(t reflect.Type, clone bool, building map[reflect.Type]bool) {
+	var (
+		fields  []reflect.StructField
+		rebuild = clone
+		embeds  []int
+	)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue // json skips them, the rebuilt struct drops them.
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Interface {
+			field.Anonymous = false // json names embedded interfaces after the type, reflect.StructOf cannot embed their methods.
+		}
+		flatten := field.Anonymous && name == ""
+		if name == "" {
+			name = field.Name
+		}
+		if p.node != nil && !flatten && !listTypes[t] {
+			if _, ok := p.node[name]; !ok {
+				rebuild = true
+				continue
+			}
+		}
+		fp := fieldPlan{index: i}
+		mask, masked := field.Tag.Lookup("mask")
+		if visible, ok := field.Tag.Lookup("visible"); ok {
+			if p.visible(visible) {
+				masked = false // the roles of the visible tag see the raw value.
+			} else if !masked {
+				rebuild = true
+				continue
+			}
+		}
+		if masked {
+			rebuild = true
+			fp.mask = &mask
+			field.Type = maskedFieldType
+		} else {
+			node := p.node
+			if listTypes[t] && field.Name != "Items" {
+				node = nil // the list fields of PageRes and CursorRes are always kept, the projection applies to the items.
+			} else if !flatten && !listTypes[t] {
+				node = p.node[name]
+			}
+			if flatten {
+				embeds = append(embeds, len(fields))
+			}
+			fp.plan = planOf(field.Type, p.scope, node, false, building)
+			p.same = p.same && fp.plan.same
+			p.identity = p.identity && fp.plan.identity
+			if !fp.plan.same {
+				field.Type = fp.plan.typ
+			}
+		}
+		field.Index, field.Offset = nil, 0
+		fields = append(fields, field)
+		p.fields = append(p.fields, fp)
+	}
+	if rebuild || !p.same {
+		p.same, p.identity = false, false
+		for _, i := range embeds {
+			if p.fields[i].plan.same {
+				p.fields[i].plan = planOf(fields[i].Type, p.scope, p.node, true, building)
+				if p.fields[i].plan.typ != nil {
+					fields[i].Type = p.fields[i].plan.typ // identity plans, e.g. embedded interfaces, keep the field type.
+				}
+			}
+		}
+		p.typ = reflect.StructOf(fields)
+	}
+}
+
+// visible reports whether the caller has any of the roles of the visible:"role:admin,role:ops" tag.
+func (p *plan) visible(tag string) bool {
+	if tag == "" {
+		return true
+	}
+	for _, item := range strings.Split(tag, ",") {
+		if role := strings.TrimPrefix(strings.TrimSpace(item), "role:"); role != item && p.scope.roles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the rewritten value of the destination type, the source value is returned untouched if nothing changed.
+func (p *plan) apply(v reflect.Value) (reflect.Value, bool) {
+	if p.identity {
+		return v, false
+	}
+	switch p.kind {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		out, changed := planOf(v.Elem().Type(), p.scope, p.node, false, nil).apply(v.Elem())
+		if !changed {
+			return v, false
+		}
+		value := reflect.New(p.typ).Elem()
+		value.Set(out)
+		return value, true
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(p.typ), !p.same
+		}
+		out, changed := p.elem.apply(v.Elem())
+		if p.same && !changed {
+			return v, false
+		}
+		value := reflect.New(p.elem.typ)
+		value.Elem().Set(out)
+		return value, true
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return reflect.Zero(p.typ), !p.same
+		}
+		var (
+			items   = make([]reflect.Value, v.Len())
+			changed = !p.same
+		)
+		for i := range items {
+			var c bool
+			items[i], c = p.elem.apply(v.Index(i))
+			changed = changed || c
+		}
+		if !changed {
+			return v, false
+		}
+		value := reflect.New(p.typ).Elem()
+		if p.kind == reflect.Slice {
+			value = reflect.MakeSlice(p.typ, len(items), len(items))
+		}
+		for i, item := range items {
+			value.Index(i).Set(item)
+		}
+		return value, true
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(p.typ), !p.same
+		}
+		value := reflect.MakeMapWithSize(p.typ, v.Len())
+		changed := !p.same
+		iter := v.MapRange()
+		for iter.Next() {
+			item, c := p.elem.apply(iter.Value())
+			changed = changed || c
+			value.SetMapIndex(iter.Key(), item)
+		}
+		if !changed {
+			return v, false
+		}
+		return value, true
+	case reflect.Struct:
+		value := reflect.New(p.typ).Elem()
+		changed := !p.same
+		if p.same {
+			value.Set(v)
+		}
+		for i, fp := range p.fields {
+			target := value.Field(i)
+			if p.same {
+				target = value.Field(fp.index)
+			}
+			if fp.mask != nil {
+				target.SetString(maskValue(*fp.mask, v.Field(fp.index)))
+				continue
+			}
+			out, c := fp.plan.apply(v.Field(fp.index))
+			if c || !p.same {
+				target.Set(out)
+			}
+			changed = changed || c
+		}
+		if !changed {
+			return v, false
+		}
+		return value, true
+	}
+	return v, false
+}
+
+// RolesFromCtxVar returns an Options.Roles reading the roles from a request context variable, e.g. Options{Roles: RolesFromCtxVar("TOKEN_ROLES")}.
+func RolesFromCtxVar(key string) func(ctx context.Context) []string {
+	return func(ctx context.Context) []string {
+		if r := ghttp.RequestFromCtx(ctx); r != nil {
+			return r.GetCtxVar(key).Strings()
+		}
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package response
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+
+	"github.com/fainc/gfe/util"
+)
+
+// Meta tags of the route request struct which override Options for the route, e.g.
+//
+//	g.Meta `path:"/pay" method:"post" format:"json" encrypt:"true" envelope:"none"`
+const (
+	MetaFormat   = "format"   // MetaFormat overrides Options.Format and the negotiated format, "custom" skips the responder.
+	MetaEncrypt  = "encrypt"  // MetaEncrypt "true" requires the payload encrypted by Options.CtxEncryptor, Options.Encryptor or ECIESEncryptor, "false" disables encryption.
+	MetaEnvelope = "envelope" // MetaEnvelope "none" writes the success payload without the result envelope, errors keep it.
+	// MetaCacheControl is written as the Cache-Control header of success responses, e.g. cache-control:"private, max-age=60".
+	MetaCacheControl = "cache-control"
+	// MetaFilename names the csv download, e.g. filename:"orders.csv", defaults to the last path segment.
+	MetaFilename = "filename"
+)
+
+// encryptor returns the Encryptor of the request.
+func (rec *responder) encryptor(r *ghttp.Request) Encryptor {
+	encryptor := rec.opts.CtxEncryptor
+	if encryptor == nil && rec.opts.Encryptor != nil {
+		encryptor = func(ctx context.Context, payload interface{}) (interface{}, bool, error) {
+			result, encrypted := rec.opts.Encryptor(payload)
+			return result, encrypted, nil
+		}
+	}
+	switch util.GetReqMetaStr(r, MetaEncrypt) {
+	case "false":
+		return nil
+	case "true":
+		if encryptor == nil {
+			encryptor = ECIESEncryptor()
+		}
+		return requireEncryption(encryptor)
+	}
+	return encryptor
+}
+
+// requireEncryption wraps the encryptor of a route declaring encrypt:"true", a payload left in clear is answered with DecryptionError,
+// e.g. ECIESEncryptor without the X-Client-Public-Key header.
+func requireEncryption(encryptor Encryptor) Encryptor {
+	return func(ctx context.Context, payload interface{}) (interface{}, bool, error) {
+		result, encrypted, err := encryptor(ctx, payload)
+		if err == nil && !encrypted {
+			return nil, false, DecryptionError(ctx, "client key required")
+		}
+		return result, encrypted, err
+	}
+}
+
+// withoutEnvelope reports whether the success payload of the request is written without the result envelope.
+func withoutEnvelope(r *ghttp.Request) bool {
+	return util.GetReqMetaStr(r, MetaEnvelope) == "none"
+}
@@ -0,0 +1,72 @@
+package response
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/gconv"
+
+	"github.com/fainc/gfe/util"
+)
+
+type acceptItem struct {
+	mime string
+	q    float64
+}
+
+// NotAcceptableError returns 406 code error. Used when no output format satisfies the Accept header.
+func NotAcceptableError(accept string) error {
+	return CodeError(http.StatusNotAcceptable, http.StatusText(http.StatusNotAcceptable), accept)
+}
+
+// resolveFormat returns the output format of the request.
+// The "format" meta tag of the request struct wins, then the configured Format is returned unless Options.Negotiate is enabled,
+// in which case the Accept header is matched by q-value, html only when Options.HTML is set. The ok result is false when the Accept header excludes every supported format.
+func (rec *responder) resolveFormat(r *ghttp.Request) (format string, ok bool) {
+	if format = util.GetReqMetaStr(r, MetaFormat); format != "" {
+		return format, true
+	}
+	if !rec.opts.Negotiate {
+		return rec.opts.Format, true
+	}
+	accept := r.GetHeader("Accept")
+	if accept == "" {
+		return rec.opts.Format, true
+	}
+	for _, item := range parseAccept(accept) {
+		switch {
+		case item.q <= 0:
+			continue
+		case formatOfMime(item.mime) == FormatHTML && rec.opts.HTML == nil:
+			continue // html is only offered once Options.HTML is configured.
+		case item.mime == "*/*" || item.mime == "application/*":
+			return rec.opts.Format, true
+		case formatOfMime(item.mime) != "":
+			return formatOfMime(item.mime), true
+		}
+	}
+	return rec.opts.Format, false
+}
+
+// parseAccept parses the Accept header and sorts the media types by q-value, keeping the client order for equal values.
+func parseAccept(accept string) (items []acceptItem) {
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		item := acceptItem{mime: strings.ToLower(strings.TrimSpace(fields[0])), q: 1}
+		for _, param := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				item.q = gconv.Float64(strings.TrimSpace(kv[1]))
+			}
+		}
+		if item.mime != "" {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+	return
+}
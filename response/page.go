@@ -0,0 +1,65 @@
+package response
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// Query parameter names of the page number and page size, used by the Link header.
+const (
+	PageQueryKey     = "page"
+	PageSizeQueryKey = "pageSize"
+)
+
+// PageRes is a paged list result, the responder writes it as {items,total,page,pageSize,pages} with Link and X-Total-Count headers.
+// Embed it in the response struct of the route, e.g. type OrderListRes struct{ *response.PageRes }.
+type PageRes struct {
+	Items    interface{} `json:"items" xml:"items" msgpack:"items"`
+	Total    int         `json:"total" xml:"total" msgpack:"total"`
+	Page     int         `json:"page" xml:"page" msgpack:"page"`
+	PageSize int         `json:"pageSize" xml:"pageSize" msgpack:"pageSize"`
+	Pages    int         `json:"pages" xml:"pages" msgpack:"pages"`
+}
+
+type pager interface {
+	page() *PageRes
+}
+
+func (p *PageRes) page() *PageRes {
+	return p
+}
+
+// setPageHeaders writes the X-Total-Count header and the RFC 8288 Link header of the first, prev, next and last pages.
+func setPageHeaders(r *ghttp.Request, p *PageRes) {
+	r.Response.Header().Set("X-Total-Count", gconv.String(p.Total))
+	if p.PageSize <= 0 {
+		return
+	}
+	var links []string
+	link := func(page int, rel string) {
+		query := r.URL.Query()
+		query.Set(PageQueryKey, gconv.String(page))
+		query.Set(PageSizeQueryKey, gconv.String(p.PageSize))
+		links = append(links, fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel))
+	}
+	link(1, "first")
+	if p.Page > 1 {
+		link(p.Page-1, "prev")
+	}
+	if p.Page < p.Pages {
+		link(p.Page+1, "next")
+	}
+	link(p.Pages, "last")
+	r.Response.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// CursorRes is a keyset paged list result, the cursors are opaque and signed.
+type CursorRes struct {
+	Items      interface{} `json:"items" xml:"items" msgpack:"items"`
+	NextCursor string      `json:"nextCursor" xml:"nextCursor" msgpack:"nextCursor"`
+	PrevCursor string      `json:"prevCursor" xml:"prevCursor" msgpack:"prevCursor"`
+	HasMore    bool        `json:"hasMore" xml:"hasMore" msgpack:"hasMore"` // HasMore reports whether more rows exist in the requested direction.
+}
@@ -0,0 +1,135 @@
+package response
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// Passthrough kinds of responses written by the helpers of this file, the responder leaves them untouched.
+const (
+	PassthroughFile     = "file"     // PassthroughFile is written by ServeFile and ServeContent.
+	PassthroughRedirect = "redirect" // PassthroughRedirect is written by Redirect.
+	PassthroughHTML     = "html"     // PassthroughHTML is written by HTML.
+)
+
+const passthroughCtxKey = "RESPONSE_PASSTHROUGH"
+
+// ContentTypeForceDownload is the legacy Content-Type of handlers writing a download themselves, it is reported as PassthroughFile.
+//
+// Deprecated: use ServeFile or ServeContent, which also support range and conditional requests.
+const ContentTypeForceDownload = "application/force-download"
+
+// FileOptions configures ServeFile and ServeContent.
+type FileOptions struct {
+	Name        string // Name is the download file name, defaults to the base name of the file.
+	Inline      bool   // Inline displays the file in the browser instead of downloading it.
+	ContentType string // ContentType defaults to the type of the name extension, or is sniffed from the content.
+	Checksum    bool   // Checksum writes the SHA-256 of the whole content as the Repr-Digest header and the ETag, it reads the content twice.
+}
+
+// Passthrough returns the passthrough kind of the request, or an empty string if the responder writes the response.
+func Passthrough(r *ghttp.Request) string {
+	if kind := r.GetCtxVar(passthroughCtxKey).String(); kind != "" {
+		return kind
+	}
+	if r.Response.Header().Get("Content-Type") == ContentTypeForceDownload {
+		return PassthroughFile // the legacy download marker, see ContentTypeForceDownload.
+	}
+	return ""
+}
+
+func setPassthrough(r *ghttp.Request, kind string) {
+	r.SetCtxVar(passthroughCtxKey, kind)
+	SetDefaultResponseHeader(r)
+}
+
+// ServeFile writes the file with Content-Disposition, range and conditional request support.
+// Return its error from the handler, e.g. a missing file is written by the responder as an error.
+func ServeFile(ctx context.Context, path string, opts ...FileOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	var opt FileOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Name == "" {
+		opt.Name = filepath.Base(path)
+	}
+	return ServeContent(ctx, f, info.ModTime(), opt)
+}
+
+// ServeContent writes the content with Content-Disposition, range and conditional request support, e.g. bytes.NewReader(data).
+// The content is written directly to the connection, bypassing the response buffer.
+func ServeContent(ctx context.Context, content io.ReadSeeker, modTime time.Time, opts ...FileOptions) error {
+	r := g.RequestFromCtx(ctx)
+	var opt FileOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	header := r.Response.Header()
+	if opt.ContentType != "" {
+		header.Set("Content-Type", opt.ContentType)
+	}
+	disposition := "attachment"
+	if opt.Inline {
+		disposition = "inline"
+	}
+	if opt.Name != "" {
+		header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": opt.Name}))
+	} else {
+		header.Set("Content-Disposition", disposition)
+	}
+	header.Set("Access-Control-Expose-Headers", "Content-Disposition")
+	if opt.Checksum {
+		sum := sha256.New()
+		if _, err := io.Copy(sum, content); err != nil {
+			return err
+		}
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		digest := base64.StdEncoding.EncodeToString(sum.Sum(nil))
+		header.Set("Repr-Digest", "sha-256=:"+digest+":") // RFC 9530, the digest of the whole content even for range responses.
+		header.Set("ETag", `"`+digest+`"`)
+	}
+	setPassthrough(r, PassthroughFile)
+	r.Response.ServeContent(opt.Name, modTime, content)
+	return nil
+}
+
+// Redirect redirects the client to the location, the status defaults to 302 Found.
+func Redirect(ctx context.Context, location string, status ...int) {
+	r := g.RequestFromCtx(ctx)
+	code := http.StatusFound
+	if len(status) > 0 {
+		code = status[0]
+	}
+	setPassthrough(r, PassthroughRedirect)
+	r.Response.Header().Set("Location", location)
+	r.Response.WriteHeader(code)
+}
+
+// HTML writes the pre-rendered HTML document with http 200.
+func HTML(ctx context.Context, html string) {
+	r := g.RequestFromCtx(ctx)
+	setPassthrough(r, PassthroughHTML)
+	r.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	r.Response.WriteStatus(http.StatusOK, html)
+}
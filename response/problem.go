@@ -0,0 +1,102 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+const defaultProblemTypeBase = "/problems/"
+
+// problemFormat is the RFC 9457 problem details object, the project error code is kept as the "code" extension member.
+type problemFormat struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     int          `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"` // Errors lists the field level validation failures.
+}
+
+// problemType returns the problem type slug and the HTTP status of an error code, the HTTPStatus of declared codes takes precedence.
+// Codes without a project meaning use "about:blank" as RFC 9457 suggests.
+func problemType(code int) (slug string, status int) {
+	slug, status = defaultProblemType(code)
+	if c, ok := LookupCode(code); ok && c.HTTPStatus > 0 {
+		status = c.HTTPStatus
+	}
+	return
+}
+
+func defaultProblemType(code int) (slug string, status int) {
+	switch {
+	case code == -1:
+		return "stand-error", http.StatusBadRequest
+	case code == gcode.CodeValidationFailed.Code():
+		return "validation-failed", http.StatusBadRequest
+	case code == 401:
+		return "unauthorized", http.StatusUnauthorized
+	case code == 402:
+		return "signature-error", http.StatusBadRequest
+	case code == CodeDecryptionError:
+		return "decryption-error", http.StatusBadRequest
+	case code >= 1000:
+		return "business-error", http.StatusBadRequest
+	case code >= 400 && code < 600:
+		return "", code
+	default:
+		return "", http.StatusInternalServerError
+	}
+}
+
+// makeProblem converts an error result into problem details.
+func (rec *responder) makeProblem(r *ghttp.Request, e *errorFormat) *problemFormat {
+	slug, status := problemType(e.Code)
+	p := &problemFormat{
+		Type:     "about:blank",
+		Title:    e.Message,
+		Status:   status,
+		Instance: r.URL.Path,
+		Code:     e.Code,
+	}
+	if fields, ok := e.Detail.([]FieldError); ok {
+		p.Errors = fields
+	} else {
+		p.Detail = problemDetail(e.Detail)
+	}
+	if slug != "" {
+		p.Type = rec.opts.ProblemTypeBase + slug
+		if rec.opts.ProblemTypeBase == "" {
+			p.Type = defaultProblemTypeBase + slug
+		}
+	}
+	return p
+}
+
+// writeProblem writes an error result as application/problem+json with the given HTTP status.
+func (rec *responder) writeProblem(r *ghttp.Request, e *errorFormat, statusCode int) {
+	p := rec.makeProblem(r, e)
+	p.Status = statusCode
+	body, _ := json.Marshal(p)
+	r.Response.WriteStatus(p.Status)
+	r.Response.ClearBuffer()
+	rec.sign(r, body)
+	r.Response.Write(body)
+	r.Response.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+}
+
+// problemDetail joins the error details into the human-readable detail string.
+func problemDetail(detail interface{}) string {
+	var details []string
+	for _, item := range gconv.Strings(detail) {
+		if item != "" {
+			details = append(details, item)
+		}
+	}
+	return strings.Join(details, "; ")
+}
@@ -0,0 +1,100 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtoMapping converts a payload into the protobuf message which is written as the envelope payload.
+type ProtoMapping func(payload interface{}) (proto.Message, error)
+
+var protoMappings sync.Map // reflect.Type => ProtoMapping
+
+// RegisterProtoMapping registers the protobuf message mapping of a payload type, e.g. RegisterProtoMapping(&v1.UserRes{}, toUserPb).
+// Payloads which implement proto.Message are written directly and need no mapping.
+func RegisterProtoMapping(payload interface{}, mapping ProtoMapping) {
+	protoMappings.Store(reflect.TypeOf(payload), mapping)
+}
+
+// protobufEncoder writes the result envelope as the following message, the payload is the serialized payload message:
+//
+//	message Result {
+//	  bool ok = 1;
+//	  bytes payload = 2; // the JSON crypt.Envelope if encrypted.
+//	  Error error = 3;
+//	  bool encrypted = 4;
+//	}
+//	message Error {
+//	  sint64 code = 1;
+//	  string message = 2;
+//	  string detail = 3; // JSON
+//	}
+type protobufEncoder struct{}
+
+func (protobufEncoder) ContentType() string { return "application/x-protobuf" }
+
+func (protobufEncoder) Encode(v interface{}) ([]byte, error) {
+	result, ok := v.(*resultFormat)
+	if !ok {
+		return marshalProtoPayload(v)
+	}
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, protowire.EncodeBool(result.Ok))
+	if result.successFormat != nil && result.Payload != nil {
+		var (
+			payload []byte
+			err     error
+		)
+		if result.Encrypted {
+			payload, err = json.Marshal(result.Payload)
+		} else {
+			payload, err = marshalProtoPayload(result.Payload)
+		}
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, payload)
+	}
+	if result.Error != nil {
+		detail, err := json.Marshal(result.Error.Detail)
+		if err != nil {
+			return nil, err
+		}
+		var e []byte
+		e = protowire.AppendTag(e, 1, protowire.VarintType)
+		e = protowire.AppendVarint(e, protowire.EncodeZigZag(int64(result.Error.Code)))
+		e = protowire.AppendTag(e, 2, protowire.BytesType)
+		e = protowire.AppendString(e, result.Error.Message)
+		e = protowire.AppendTag(e, 3, protowire.BytesType)
+		e = protowire.AppendBytes(e, detail)
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, e)
+	}
+	if result.Encrypted {
+		b = protowire.AppendTag(b, 4, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(true))
+	}
+	return b, nil
+}
+
+func marshalProtoPayload(payload interface{}) ([]byte, error) {
+	if m, ok := payload.(proto.Message); ok {
+		return proto.Marshal(m)
+	}
+	mapping, ok := protoMappings.Load(reflect.TypeOf(payload))
+	if !ok {
+		return nil, fmt.Errorf("no protobuf mapping registered for %T", payload)
+	}
+	m, err := mapping.(ProtoMapping)(payload)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(m)
+}
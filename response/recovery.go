@@ -0,0 +1,23 @@
+package response
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/os/gctx"
+)
+
+// panicDetail is the error detail of a recovered panic, ErrorID is the trace id of the request which is also written to the error log.
+type panicDetail struct {
+	ErrorID string `json:"errorId" xml:"errorId" msgpack:"errorId"`
+}
+
+// ErrorID returns the error id of the request, which is the trace id.
+func ErrorID(ctx context.Context) string {
+	return gctx.CtxId(ctx)
+}
+
+// panicError converts a panic recovered by the server into an InternalError carrying the error id,
+// the DetailPolicy decides whether the panic message and stack are written.
+func (rec *responder) panicError(ctx context.Context, err error) error {
+	return rec.unexpectedError(err, panicDetail{ErrorID: ErrorID(ctx)})
+}
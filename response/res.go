@@ -35,7 +35,7 @@ func CodeErrorTranslate(ctx context.Context, code int, message string, detail ..
 
 // CodeErrorTranslateFormat returns an error with i18n format message.
 func CodeErrorTranslateFormat(ctx context.Context, code int, format string, values ...interface{}) error {
-	message := gi18n.Tf(ctx, format, values)
+	message := gi18n.Tf(ctx, format, values...)
 	return CodeError(code, message, nil)
 }
 
@@ -49,6 +49,21 @@ func SignatureError(ctx context.Context, detail ...interface{}) error {
 	return CodeErrorTranslate(ctx, 402, "SignatureError", detail...)
 }
 
+// DecryptionError returns 420 code error. Used when an encrypted request body cannot be decrypted, or the response cannot be encrypted to the client key.
+func DecryptionError(ctx context.Context, detail ...interface{}) error {
+	return CodeErrorTranslate(ctx, CodeDecryptionError, "DecryptionError", detail...)
+}
+
+// IdempotencyConflictError returns 409 code error. Used when a request with the same Idempotency-Key is in progress.
+func IdempotencyConflictError(ctx context.Context, detail ...interface{}) error {
+	return CodeErrorTranslate(ctx, http.StatusConflict, "IdempotencyConflict", detail...)
+}
+
+// IdempotencyKeyReusedError returns 422 code error. Used when an Idempotency-Key is reused with a different request body.
+func IdempotencyKeyReusedError(ctx context.Context, detail ...interface{}) error {
+	return CodeErrorTranslate(ctx, http.StatusUnprocessableEntity, "IdempotencyKeyReused", detail...)
+}
+
 // InternalError returns 500 code error. Used by server error.
 func InternalError(detail ...interface{}) error {
 	return CodeError(500, http.StatusText(500), detail...)
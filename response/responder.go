@@ -2,10 +2,8 @@ package response
 
 import (
 	"context"
-	"fmt"
 	"net/http"
-
-	"github.com/vmihailenco/msgpack/v5"
+	"time"
 
 	"github.com/gogf/gf/v2/errors/gcode"
 	"github.com/gogf/gf/v2/frame/g"
@@ -13,19 +11,28 @@ import (
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/net/ghttp"
 	"github.com/gogf/gf/v2/text/gstr"
+
+	"github.com/fainc/gfe/crypt"
 )
 
 const (
-	FormatJSON    = "json"
-	FormatXML     = "xml"
-	FormatCustom  = "custom"
-	FormatMsgPack = "msgPack" // MessagePack https://msgpack.org
+	FormatJSON     = "json"
+	FormatXML      = "xml"
+	FormatCustom   = "custom"
+	FormatMsgPack  = "msgPack"  // MessagePack https://msgpack.org
+	FormatYAML     = "yaml"     // YAML https://yaml.org
+	FormatCBOR     = "cbor"     // CBOR https://cbor.io
+	FormatProtobuf = "protobuf" // Protocol Buffers, see RegisterProtoMapping.
+	FormatCSV      = "csv"      // CSV of list payloads, errors are written as JSON.
+	FormatHTML     = "html"     // HTML rendered by gview templates, see HTMLOptions and MetaTemplate.
 )
 
 type errorFormat struct {
 	Code    int         `json:"code" msgpack:"code"`
 	Message string      `json:"message" msgpack:"message"`
 	Detail  interface{} `json:"detail" msgpack:"detail"`
+	Chain   []string    `json:"chain,omitempty" msgpack:"chain,omitempty"`
+	Stack   string      `json:"stack,omitempty" msgpack:"stack,omitempty"`
 }
 type successFormat struct {
 	Payload interface{} `json:"payload" msgpack:"payload"`
@@ -43,11 +50,35 @@ type responder struct {
 type Options struct {
 	Format    string
 	Encryptor func(payload interface{}) (result interface{}, encrypted bool)
+	// CtxEncryptor takes precedence over Encryptor and receives the request context, e.g. SealEncryptor or ECIESEncryptor.
+	CtxEncryptor Encryptor
+	Negotiate    bool // Negotiate picks a registered format per request from the "format" meta tag or the Accept header, Format is the fallback.
+	// ProblemDetails writes errors as RFC 9457 application/problem+json with the real HTTP status instead of the result envelope.
+	ProblemDetails  bool
+	ProblemTypeBase string // ProblemTypeBase prefixes the problem type slugs, defaults to "/problems/".
+	StatusPolicy    StatusPolicy
+	StatusMapper    StatusMapper     // StatusMapper takes precedence over StatusPolicy and the problem type status.
+	ETag            bool             // ETag answers conditional GET requests with 304, see SetLastModified and MetaCacheControl.
+	Compress        *CompressOptions // Compress negotiates gzip / deflate / br from the Accept-Encoding header, nil disables it.
+	// Roles returns the caller roles matched by the visible:"role:admin" struct tags, see RolesFromCtxVar.
+	// Fields tagged visible are dropped for other callers, or masked if they are also tagged with mask:"phone".
+	Roles      func(ctx context.Context) []string
+	Projection bool // Projection enables the sparse fields query, see FieldsQueryKey.
+	// Signer signs the serialized body before compression, clients verify it with crypt.VerifyResponse.
+	Signer crypt.Signer
+	HTML   *HTMLOptions
+	// Development selects DevelopmentDetail when DetailPolicy is nil, e.g. Development: gmode.IsDevelop().
+	Development  bool
+	DetailPolicy *DetailPolicy
+	Redact       Redactor        // Redact rewrites every error detail before it is written.
+	Envelope     EnvelopeBuilder // Envelope defaults to DefaultEnvelope.
 }
 
 // NewResponder returns a responder.
-// Options.Format supports json / xml / msgPack / custom and works within the scope of middleware.
+// The format, encrypt and envelope meta tags of a route override the options for the route, see MetaFormat.
+// Options.Format supports json / xml / msgPack / yaml / cbor / protobuf / csv / html / custom and any format added by RegisterEncoder, and works within the scope of middleware.
 // The "custom" tag specifies that middleware handler should be skipped, and you can write content to the response buffer yourself.
+// With Options.Negotiate enabled, requests whose Accept header excludes every supported format receive a 406 error.
 func NewResponder(opts Options) *responder {
 	r := &responder{opts}
 	return r
@@ -55,28 +86,49 @@ func NewResponder(opts Options) *responder {
 
 // Middleware handler of response.
 func (rec *responder) Middleware(r *ghttp.Request) {
+	runWritten := trackWritten(r)
 	r.Middleware.Next()
+	returned := false
+	defer func() {
+		// r.ExitAll exits by panicking once the response is written, other panics skip the callbacks,
+		// so that a half-written response is never captured.
+		if returned || r.IsExited() {
+			runWritten()
+		}
+	}()
+	rec.respond(r)
+	returned = true
+}
+
+// respond writes the handler response or error.
+func (rec *responder) respond(r *ghttp.Request) {
 	var (
-		ctx  = r.Context()
-		err  = r.GetError()
-		res  = r.GetHandlerResponse()
-		code = gerror.Code(err)
+		ctx = r.Context()
+		err = r.GetError()
+		res = r.GetHandlerResponse()
 	)
+	format, acceptable := rec.resolveFormat(r)
 	if r.IsExited() ||
-		rec.opts.Format == FormatCustom ||
+		format == FormatCustom ||
 		gstr.Contains(r.RequestURI, "api.json") ||
 		gstr.Contains(r.RequestURI, "/debug/pprof/") ||
-		r.Response.Writer.Header().Get("Content-Type") == "application/force-download" {
+		Passthrough(r) != "" {
+		return
+	}
+	if !acceptable {
+		notAcceptableError := NotAcceptableError(r.GetHeader("Accept"))
+		rec.Write(ctx, http.StatusNotAcceptable, nil, notAcceptableError)
+		r.SetError(notAcceptableError)
+		return
+	}
+	if s, ok := res.(streamer); ok && err == nil && s.stream() != nil {
+		rec.writeStream(r, s.stream())
+		r.ExitAll()
 		return
 	}
 	if err != nil {
-		// Normal error code. The CodeValidationFailed 51 is special.
-		if code.Code() == -1 || code.Code() >= 1000 || code.Code() == gcode.CodeValidationFailed.Code() || code.Code() >= 400 && code.Code() < 500 {
-			rec.Write(ctx, http.StatusOK, nil, err)
-			return
-		}
-		// Other unexpected error code, writes http 500 InternalError and removes error details.
-		rec.Write(ctx, http.StatusInternalServerError, nil, CodeError(500, "InternalServerError", fmt.Sprintf("Unexpected Error Code %v", code.Code())))
+		statusCode, writtenErr := rec.failure(ctx, err)
+		rec.Write(ctx, statusCode, nil, writtenErr)
 		return
 	}
 	if r.Response.Status > 0 && r.Response.Status != http.StatusOK {
@@ -88,44 +140,156 @@ func (rec *responder) Middleware(r *ghttp.Request) {
 	rec.Write(ctx, http.StatusOK, res, nil)
 }
 
+// failure returns the http status and the error written for a handler error.
+func (rec *responder) failure(ctx context.Context, err error) (statusCode int, writtenErr error) {
+	code := gerror.Code(err)
+	if code == gcode.CodeInternalPanic {
+		// The handler panicked, writes http 500 InternalError with the error id which is logged by the Logger middleware.
+		return http.StatusInternalServerError, rec.panicError(ctx, err)
+	}
+	// Normal error code. The CodeValidationFailed 51 is special.
+	if code.Code() == -1 || code.Code() >= 1000 || code.Code() == gcode.CodeValidationFailed.Code() || code.Code() >= 400 && code.Code() < 500 {
+		return http.StatusOK, err
+	}
+	// Other unexpected error code, writes http 500 InternalError and removes error details unless the DetailPolicy exposes them.
+	return http.StatusInternalServerError, rec.unexpectedError(err)
+}
+
 // Write formatted data to response buffer.
 func (rec *responder) Write(ctx context.Context, statusCode int, payload interface{}, err error) {
 	result := rec.makeResult(payload, err)
 	r := g.RequestFromCtx(ctx)
-	r.Response.WriteStatus(statusCode) // use http 200
-	r.Response.ClearBuffer()
-	if rec.opts.Encryptor != nil && result.Ok {
-		result.Payload, result.Encrypted = rec.opts.Encryptor(result.Payload) // call the encryptor function.
-	}
-	switch rec.opts.Format {
-	case FormatXML:
-		r.Response.WriteXml(result, "xml")
-		r.Response.Header().Set("Content-Type", "application/xml; charset=utf-8") // Overwrite the default XML content type "text/xml" in GF with "application/xml".
-	case FormatMsgPack:
-		marshal, _ := msgpack.Marshal(result)
-		r.Response.Write(marshal)
-		r.Response.Header().Set("Content-Type", "application/x-msgpack")
-	default:
-		r.Response.WriteJson(result)
-		r.Response.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if format, _ := rec.resolveFormat(r); result.Ok {
+		if err = rec.prepare(r, format, result); err != nil {
+			// The payload cannot be encrypted, writes the error instead of a plain payload.
+			statusCode, err = rec.failure(ctx, err)
+			result = rec.makeResult(nil, err)
+			r.SetError(err) // Set an error for the next middleware, e.g. logs middleware.
+		}
+	}
+	if !result.Ok {
+		if rec.opts.ProblemDetails {
+			_, statusCode = problemType(result.Error.Code) // problem details always use the real HTTP status.
+		}
+		statusCode = rec.errorStatus(gerror.Code(err), statusCode)
+	}
+	if p, ok := payload.(pager); ok && result.Ok && p.page() != nil {
+		setPageHeaders(r, p.page())
+	}
+	if format, _ := rec.resolveFormat(r); rec.opts.ProblemDetails && !result.Ok && format != FormatHTML {
+		rec.writeProblem(r, result.Error, statusCode) // browsers negotiating html receive the error page instead.
+	} else {
+		rec.writeResult(r, statusCode, result)
+	}
+	if rec.opts.Negotiate {
+		r.Response.Header().Add("Vary", "Accept")
 	}
 	SetDefaultResponseHeader(r)
 	r.ExitAll() // r.IsExited() will return a true value in the next middleware.
 }
 
+// writeResult writes the result envelope with the encoder of the request format.
+func (rec *responder) writeResult(r *ghttp.Request, statusCode int, result *resultFormat) {
+	r.Response.WriteStatus(statusCode) // use http 200
+	r.Response.ClearBuffer()
+	format, _ := rec.resolveFormat(r) // unacceptable requests fall back to the configured format.
+	encoder := getEncoder(format)
+	if format == FormatHTML {
+		encoder = rec.htmlEncoder(r, statusCode, result)
+	}
+	var v interface{} = result
+	if _, ok := encoder.(htmlEncoder); !ok {
+		v = rec.envelope(result) // html templates read the result itself.
+	}
+	if result.Ok && withoutEnvelope(r) {
+		v = result.Payload
+	} else if raw, ok := encoder.(RawEncoder); ok && raw.Raw() {
+		if result.Ok && !result.Encrypted {
+			v = result.Payload
+		} else {
+			encoder = getEncoder(FormatJSON)
+		}
+	}
+	body, encodeErr := encoder.Encode(v)
+	if encodeErr != nil {
+		// The payload cannot be written in the requested format, writes http 500 InternalError in JSON.
+		internalError := InternalError(encodeErr.Error())
+		encoder = getEncoder(FormatJSON)
+		body, _ = encoder.Encode(rec.envelope(rec.makeResult(nil, internalError)))
+		r.Response.WriteStatus(http.StatusInternalServerError)
+		r.Response.ClearBuffer()
+		r.SetError(internalError)
+	}
+	r.Response.Header().Set("Content-Type", encoder.ContentType())
+	if _, ok := encoder.(csvEncoder); ok && encodeErr == nil {
+		setCSVAttachment(r)
+	}
+	encoding := rec.contentEncoding(r, body, encoder.ContentType())
+	if encodeErr == nil && result.Ok {
+		setCacheControl(r)
+	}
+	if rec.opts.ETag && encodeErr == nil && result.Ok && statusCode == http.StatusOK && rec.conditional(r, body, encoding) {
+		r.Response.WriteStatus(http.StatusNotModified)
+		r.Response.ClearBuffer()
+		return
+	}
+	rec.sign(r, body)
+	setWrittenBody(r, body)
+	r.Response.Write(compressBody(r, body, encoding))
+}
+
+// prepare applies the projection and the encryptor of the request to the success payload written in the format.
+func (rec *responder) prepare(r *ghttp.Request, format string, result *resultFormat) (err error) {
+	if format != FormatProtobuf {
+		result.Payload = rec.project(r, result.Payload) // protobuf messages have a fixed schema, mask them in the ProtoMapping.
+	}
+	if encryptor := rec.encryptor(r); encryptor != nil {
+		result.Payload, result.Encrypted, err = encryptor(r.Context(), result.Payload) // call the encryptor function.
+	}
+	return
+}
+
+// sign writes the signature headers of the body, the method, request target and status are signed as well.
+func (rec *responder) sign(r *ghttp.Request, body []byte) {
+	if rec.opts.Signer == nil {
+		return
+	}
+	req := crypt.SignedRequest{Method: r.Method, Target: r.URL.RequestURI(), Status: r.Response.Status}
+	if err := crypt.SignHeader(rec.opts.Signer, r.Response.Header(), req, body, time.Now()); err != nil {
+		panic(err.Error()) // never write an unsigned body once signing is expected.
+	}
+}
+
 func (rec *responder) makeResult(payload interface{}, err error) (result *resultFormat) {
 	result = &resultFormat{}
 	if err != nil {
-		ge := gerror.Code(err)
+		var (
+			ge     = gerror.Code(err)
+			policy = rec.detailPolicy()
+		)
 		result.Ok = false
 		e := &errorFormat{
 			Code:    ge.Code(),
 			Message: err.Error(),
-			Detail:  ge.Detail(),
+		}
+		if policy.Detail || ge.Code() == 500 {
+			e.Detail = ge.Detail() // the detail of InternalError is written by the responder itself, e.g. the error id.
+		}
+		if fields := validationFields(err); fields != nil {
+			e.Detail = fields
+		}
+		if rec.opts.Redact != nil {
+			e.Detail = rec.opts.Redact(e.Code, e.Detail)
 		}
 		if e.Detail == nil || e.Detail == "" {
 			e.Detail = []int{}
 		}
+		if policy.Chain {
+			e.Chain = errorChain(err)
+		}
+		if policy.Stack && gerror.HasStack(err) {
+			e.Stack = gerror.Stack(err)
+		}
 		result.Error = e
 	} else {
 		result.successFormat = &successFormat{Payload: payload}
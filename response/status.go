@@ -0,0 +1,35 @@
+package response
+
+import (
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+// StatusPolicy decides the HTTP status of the business errors written by the responder.
+type StatusPolicy int
+
+const (
+	StatusAlways200 StatusPolicy = iota // StatusAlways200 writes http 200 for -1, 51, 4xx and >=1000 codes, which is the default.
+	StatusReal4xx                       // StatusReal4xx writes the code itself as HTTP status for 400-499 codes.
+	StatusDeclared                      // StatusDeclared writes the HTTPStatus of declared codes, see RegisterCode, and behaves as StatusReal4xx for the others.
+)
+
+// StatusMapper maps an error code to the HTTP status, returning 0 keeps the status decided by the StatusPolicy.
+type StatusMapper func(code gcode.Code) int
+
+// errorStatus returns the HTTP status of an error result, statusCode is the status decided by the middleware.
+func (rec *responder) errorStatus(code gcode.Code, statusCode int) int {
+	if rec.opts.StatusMapper != nil {
+		if status := rec.opts.StatusMapper(code); status > 0 {
+			return status
+		}
+	}
+	if rec.opts.StatusPolicy == StatusDeclared {
+		if c, ok := LookupCode(code.Code()); ok && c.HTTPStatus > 0 {
+			return c.HTTPStatus
+		}
+	}
+	if rec.opts.StatusPolicy != StatusAlways200 && code.Code() >= 400 && code.Code() < 500 {
+		return code.Code()
+	}
+	return statusCode
+}
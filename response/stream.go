@@ -0,0 +1,162 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+const (
+	StreamSSE    = "sse"    // Server-Sent Events, text/event-stream.
+	StreamNDJSON = "ndjson" // Newline delimited JSON, application/x-ndjson.
+)
+
+const defaultHeartbeat = 15 * time.Second
+
+// Stream is a streaming handler result, the responder writes every item as a result envelope until the items end or the client disconnects.
+// Embed it in the response struct of the route, e.g. type ExportRes struct{ *response.Stream }, and return &ExportRes{response.NewStream(response.StreamSSE, ch)}.
+// An error item is written as an error envelope and ends the stream.
+type Stream struct {
+	Mode      string
+	Heartbeat time.Duration // Heartbeat is the idle interval of keep-alive writes, defaults to 15s, a negative value disables it.
+	items     <-chan interface{}
+	next      func(ctx context.Context) (item interface{}, err error)
+}
+
+type streamer interface {
+	stream() *Stream
+}
+
+// NewStream returns a Stream which writes the items of a channel, the producer closes the channel to end the stream.
+// The producer should also watch the request context, which is done when the client disconnects.
+func NewStream(mode string, items <-chan interface{}) *Stream {
+	return &Stream{Mode: mode, items: items}
+}
+
+// NewStreamFunc returns a Stream which writes the items returned by next, next returns io.EOF to end the stream.
+func NewStreamFunc(mode string, next func(ctx context.Context) (item interface{}, err error)) *Stream {
+	return &Stream{Mode: mode, next: next}
+}
+
+func (s *Stream) stream() *Stream {
+	return s
+}
+
+// writeStream writes the stream items, the response is flushed after every item so the buffer stays empty.
+func (rec *responder) writeStream(r *ghttp.Request, s *Stream) {
+	ctx := r.Context()
+	header := r.Response.Header()
+	if s.Mode == StreamSSE {
+		header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	} else {
+		header.Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	}
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // disable proxy buffering, e.g. nginx.
+	SetDefaultResponseHeader(r)
+	r.Response.ClearBuffer()
+	r.Response.WriteHeader(http.StatusOK)
+	r.Response.Flush()
+
+	items := s.items
+	if s.next != nil {
+		items = iterate(ctx, s.next)
+	}
+	heartbeat := s.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = defaultHeartbeat
+	}
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return // the client disconnects.
+		case <-tick:
+			if s.Mode == StreamSSE {
+				r.Response.Write(": heartbeat\n\n")
+			} else {
+				r.Response.Write("\n") // ndjson clients skip empty lines.
+			}
+			r.Response.Flush()
+		case item, ok := <-items:
+			if !ok {
+				if s.Mode == StreamSSE {
+					r.Response.Write("event: end\ndata: {}\n\n")
+					r.Response.Flush()
+				}
+				return
+			}
+			err, _ := item.(error)
+			if err != nil {
+				item = nil
+			}
+			if err = rec.writeStreamItem(r, s.Mode, item, err); err != nil {
+				r.SetError(err) // Set an error for the next middleware, e.g. logs middleware.
+				return
+			}
+		}
+	}
+}
+
+// writeStreamItem writes an item as the result envelope of an event or line, and returns the error written.
+// The item is projected and encrypted like the payload of other routes.
+func (rec *responder) writeStreamItem(r *ghttp.Request, mode string, item interface{}, err error) error {
+	result := rec.makeResult(item, err)
+	if result.Ok {
+		if err = rec.prepare(r, FormatJSON, result); err != nil {
+			_, err = rec.failure(r.Context(), err) // the item cannot be encrypted, writes the error and ends the stream.
+			result = rec.makeResult(nil, err)
+		}
+	}
+	body, encodeErr := getEncoder(FormatJSON).Encode(rec.envelope(result))
+	if encodeErr != nil {
+		body, _ = getEncoder(FormatJSON).Encode(rec.envelope(rec.makeResult(nil, InternalError(encodeErr.Error()))))
+	}
+	if mode == StreamSSE {
+		event := "message"
+		if !result.Ok {
+			event = "error"
+		}
+		r.Response.Write(fmt.Sprintf("event: %s\ndata: %s\n\n", event, body))
+	} else {
+		r.Response.Write(body, "\n")
+	}
+	r.Response.Flush()
+	return err
+}
+
+// iterate runs next in a goroutine and sends the items to the returned channel until io.EOF, an error or the context is done.
+func iterate(ctx context.Context, next func(ctx context.Context) (item interface{}, err error)) <-chan interface{} {
+	items := make(chan interface{})
+	go func() {
+		defer close(items)
+		for {
+			item, err := next(ctx)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				item = err
+			}
+			select {
+			case items <- item:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return items
+}
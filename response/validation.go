@@ -0,0 +1,38 @@
+package response
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/gogf/gf/v2/util/gvalid"
+)
+
+// FieldError is a field level validation failure written as the error detail of code 51.
+type FieldError struct {
+	Field   string `json:"field" xml:"field" msgpack:"field"`
+	Rule    string `json:"rule" xml:"rule" msgpack:"rule"`
+	Message string `json:"message" xml:"message" msgpack:"message"`
+}
+
+// validationFields returns the field errors of a gvalid error, or nil if err is not a validation error.
+// The messages are translated by gvalid with the request language, using the "gf.gvalid.rule.*" i18n keys, see i18n_example.toml.
+func validationFields(err error) []FieldError {
+	var validationError gvalid.Error
+	if !errors.As(err, &validationError) {
+		return nil
+	}
+	fields := make([]FieldError, 0)
+	for _, item := range validationError.Items() {
+		for field, ruleErrors := range item {
+			rules := make([]string, 0, len(ruleErrors))
+			for rule := range ruleErrors {
+				rules = append(rules, rule)
+			}
+			sort.Strings(rules)
+			for _, rule := range rules {
+				fields = append(fields, FieldError{Field: field, Rule: rule, Message: ruleErrors[rule].Error()})
+			}
+		}
+	}
+	return fields
+}
@@ -0,0 +1,51 @@
+package response
+
+import (
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+const writtenCtxKey = "RESPONSE_WRITTEN_HOOKS"
+
+type writtenHooks struct {
+	hooks []func(r *ghttp.Request)
+	body  []byte // body is the serialized body before compression.
+}
+
+// OnWritten registers a callback of the request which runs after the responder middleware has written the response,
+// including skipped, streamed and passthrough responses, e.g. to capture the buffered response.
+// The callbacks do not run if the responder panics, e.g. on a Signer error.
+// It reports false and ignores the callback if the request is not wrapped by the responder middleware.
+func OnWritten(r *ghttp.Request, hook func(r *ghttp.Request)) bool {
+	w, ok := r.GetCtxVar(writtenCtxKey).Val().(*writtenHooks)
+	if ok {
+		w.hooks = append(w.hooks, hook)
+	}
+	return ok
+}
+
+// WrittenBody returns the body written by the responder before compression, e.g. to store the response in an OnWritten callback.
+// It returns the response buffer if the responder has not serialized a body.
+func WrittenBody(r *ghttp.Request) []byte {
+	if w, ok := r.GetCtxVar(writtenCtxKey).Val().(*writtenHooks); ok && w.body != nil {
+		return w.body
+	}
+	return r.Response.Buffer()
+}
+
+// setWrittenBody records the body before compression.
+func setWrittenBody(r *ghttp.Request, body []byte) {
+	if w, ok := r.GetCtxVar(writtenCtxKey).Val().(*writtenHooks); ok {
+		w.body = body
+	}
+}
+
+// trackWritten marks the request as wrapped by the responder and returns the function running the callbacks.
+func trackWritten(r *ghttp.Request) (run func()) {
+	w := &writtenHooks{}
+	r.SetCtxVar(writtenCtxKey, w)
+	return func() {
+		for _, hook := range w.hooks {
+			hook(r)
+		}
+	}
+}
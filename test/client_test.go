@@ -0,0 +1,41 @@
+package test
+
+import (
+	"context"
+	ecdsa2 "crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+
+	"github.com/fainc/gfe/client"
+	"github.com/fainc/gfe/response"
+)
+
+func TestClient(t *testing.T) {
+	c := startServer(t, response.Options{Format: response.FormatJSON, Negotiate: true, CtxEncryptor: response.ECIESEncryptor()}, userApi{})
+	for _, format := range []string{client.FormatJSON, client.FormatMsgPack} {
+		res, err := client.Post[userRes](context.Background(), client.New(c, client.Options{Format: format}), "/user", g.Map{"name": "alice"})
+		if err != nil || res.Name != "alice" {
+			t.Fatalf("unexpected result %+v, err %v", res, err)
+		}
+	}
+	_, err := client.Post[userRes](context.Background(), client.New(c), "/user", g.Map{"phone": "1"})
+	if code := gerror.Code(err); code.Code() != 51 || code.Detail() == nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	key, err := ecdsa2.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.New(c, client.Options{ClientKey: key}).Do(context.Background(), "POST", "/user", g.Map{"name": "bob"})
+	if err != nil || !result.Encrypted {
+		t.Fatalf("unexpected encrypted result %+v, err %v", result, err)
+	}
+	var res userRes
+	if err = result.Decode(&res); err != nil || res.Name != "bob" {
+		t.Fatalf("unexpected payload %+v, err %v", res, err)
+	}
+}
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+
+	"github.com/fainc/gfe/response"
+)
+
+// codeSeq keeps the declared code unique when the test runs repeatedly, e.g. go test -count=2, the registry is global.
+var codeSeq int32 = 1041
+
+func TestRegisterCode(t *testing.T) {
+	code := int(atomic.AddInt32(&codeSeq, 1))
+	c := response.Code{Code: code, MessageKey: "OrderClosed", HTTPStatus: 409, Description: "Order is closed"}
+	if err := response.RegisterCode(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := response.RegisterCode(c); err == nil {
+		t.Fatal("expected duplicate registration error")
+	}
+	if err := response.RegisterCode(response.Code{Code: 200}); err == nil {
+		t.Fatal("expected out of range error")
+	}
+	if got := gerror.Code(response.DeclaredError(context.Background(), code)).Code(); got != code {
+		t.Fatalf("unexpected code %d", got)
+	}
+	if got := gerror.Code(response.DeclaredError(context.Background(), 99999)).Code(); got != 500 {
+		t.Fatalf("undeclared code should be 500, got %d", got)
+	}
+	if !strings.Contains(response.CodesMarkdown(), fmt.Sprintf("| %d | 409 | OrderClosed | Order is closed |", code)) {
+		t.Fatal("markdown misses the declared code")
+	}
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	ecdsa2 "crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/fainc/go-crypto/keypair"
+
+	"github.com/fainc/gfe/crypt"
+)
+
+func TestECDHEnvelope(t *testing.T) {
+	pri, err := ecdsa2.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := crypt.SealECDH(&pri.PublicKey, "k1", []byte(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := crypt.NewECDHDecryptor(pri, "k1").Decrypt(env)
+	if err != nil || string(plain) != `{"id":1}` {
+		t.Fatalf("unexpected plain %s, err %v", plain, err)
+	}
+	env.KeyID = "k2"
+	if _, err = crypt.NewECDHDecryptor(pri, "k1").Decrypt(env); err == nil {
+		t.Fatal("expected key id mismatch")
+	}
+}
+
+func TestSM2Envelope(t *testing.T) {
+	kp, err := keypair.GenSM2KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := crypt.SealSM2(kp.Public.ToHexString(), "", []byte(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := crypt.NewSM2Decryptor(kp.Private.ToHexString(), "").Decrypt(env)
+	if err != nil || string(plain) != `{"id":1}` {
+		t.Fatalf("unexpected plain %s, err %v", plain, err)
+	}
+	cipherText, _ := base64.StdEncoding.DecodeString(env.CipherText)
+	cipherText[0] ^= 1
+	env.CipherText = base64.StdEncoding.EncodeToString(cipherText)
+	if _, err = crypt.NewSM2Decryptor(kp.Private.ToHexString(), "").Decrypt(env); err == nil {
+		t.Fatal("expected tampered cipher text to be rejected")
+	}
+}
+
+func TestSharedKeyEnvelope(t *testing.T) {
+	for _, c := range []interface {
+		crypt.Sealer
+		crypt.Decryptor
+	}{
+		crypt.NewAESGCM([]byte("0123456789abcdef0123456789abcdef"), "aes"),
+		crypt.NewSM4([]byte("0123456789abcdef"), "sm4"),
+	} {
+		env, err := c.Seal([]byte(`{"id":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out struct{ ID int }
+		if err = crypt.Open(c, env, &out); err != nil || out.ID != 1 {
+			t.Fatalf("%s: unexpected result %+v, err %v", c.Alg(), out, err)
+		}
+	}
+}
+
+func TestClientPublicKeyHeader(t *testing.T) {
+	pri, err := ecdsa2.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := crypt.ClientPublicKeyHeader(&pri.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := crypt.ParseClientPublicKey(header)
+	if err != nil || !pub.Equal(&pri.PublicKey) {
+		t.Fatalf("unexpected public key, err %v", err)
+	}
+	env, err := crypt.SealECDH(pub, "", []byte(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct{ ID int }
+	if err = crypt.Open(crypt.NewECDHDecryptor(pri, ""), env, &out); err != nil || out.ID != 1 {
+		t.Fatalf("unexpected result %+v, err %v", out, err)
+	}
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/guid"
+
+	"github.com/fainc/gfe/crypt"
+	"github.com/fainc/gfe/middleware"
+	"github.com/fainc/gfe/response"
+)
+
+type secretReq struct {
+	g.Meta `path:"/secret" method:"post" x-decrypt:"true"`
+	Name   string `v:"required" json:"name"`
+}
+
+type echoReq struct {
+	g.Meta `path:"/echo" method:"post"`
+	Name   string `v:"required" json:"name"`
+}
+
+type decryptApi struct{}
+
+func (decryptApi) Secret(ctx context.Context, req *secretReq) (res *userRes, err error) {
+	return &userRes{Name: req.Name}, nil
+}
+
+func (decryptApi) Echo(ctx context.Context, req *echoReq) (res *userRes, err error) {
+	return &userRes{Name: req.Name}, nil
+}
+
+func TestDecryptor(t *testing.T) {
+	pri, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := g.Server(guid.S())
+	s.SetDumpRouterMap(false)
+	s.SetAccessLogEnabled(false)
+	s.SetErrorLogEnabled(false)
+	s.SetPort(0)
+	s.Group("/", func(group *ghttp.RouterGroup) {
+		group.Middleware(response.NewResponder(response.Options{Format: response.FormatJSON}).Middleware)
+		group.Middleware(middleware.Decryptor(crypt.NewECDHDecryptor(pri, "k1")).Register)
+		group.Bind(decryptApi{})
+	})
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown() })
+	time.Sleep(100 * time.Millisecond)
+	client := g.Client()
+	client.SetPrefix(fmt.Sprintf("http://127.0.0.1:%d", s.GetListenedPort()))
+	seal := func(plain string) *crypt.Envelope {
+		env, err := crypt.SealECDH(&pri.PublicKey, "k1", []byte(plain))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return env
+	}
+	post := func(url string, header map[string]string, data interface{}) string {
+		res, err := client.ContentJson().Header(header).Post(context.Background(), url, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Close()
+		return res.ReadAllString()
+	}
+	tampered := seal(`{"name":"alice"}`)
+	cipherText, _ := base64.StdEncoding.DecodeString(tampered.CipherText)
+	cipherText[0] ^= 0xff
+	tampered.CipherText = base64.StdEncoding.EncodeToString(cipherText)
+	unsupported := seal(`{"name":"alice"}`)
+	unsupported.Alg = "none"
+	for _, c := range []struct {
+		name   string
+		url    string
+		header map[string]string
+		data   interface{}
+		expect string
+	}{
+		// the decrypted body replaces the request body and is parsed into the req struct.
+		{"decrypted", "/secret", nil, seal(`{"name":"alice"}`), `{"ok":true,"payload":{"name":"alice"}}`},
+		{"validated", "/secret", nil, seal(`{}`), `{"ok":false,"error":{"code":51,"message":"The Name field is required","detail":[{"field":"Name","rule":"required","message":"The Name field is required"}]}}`},
+		// x-decrypt routes reject plain bodies.
+		{"plain", "/secret", nil, g.Map{"name": "alice"}, `{"ok":false,"error":{"code":420,"message":"DecryptionError","detail":["request body is not an encrypted envelope"]}}`},
+		{"unsupported", "/secret", nil, unsupported, `{"ok":false,"error":{"code":420,"message":"DecryptionError","detail":["unsupported alg none"]}}`},
+		// a tampered envelope is rejected without the reason.
+		{"tampered", "/secret", nil, tampered, `{"ok":false,"error":{"code":420,"message":"DecryptionError","detail":["request body cannot be decrypted"]}}`},
+		// other routes decrypt only with the X-Encrypted header.
+		{"optional plain", "/echo", nil, g.Map{"name": "bob"}, `{"ok":true,"payload":{"name":"bob"}}`},
+		{"optional encrypted", "/echo", map[string]string{"X-Encrypted": "true"}, seal(`{"name":"bob"}`), `{"ok":true,"payload":{"name":"bob"}}`},
+	} {
+		data, _ := json.Marshal(c.data)
+		if body := post(c.url, c.header, data); body != c.expect {
+			t.Fatalf("%s: unexpected response %s", c.name, body)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/gclient"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/fainc/gfe/response"
+)
+
+type userListReq struct {
+	g.Meta `path:"/users" method:"get"`
+}
+
+type userListRes struct {
+	List []userRes `json:"list"`
+}
+
+type userListApi struct{}
+
+func (userListApi) UserList(ctx context.Context, req *userListReq) (res *userListRes, err error) {
+	return &userListRes{List: []userRes{{Name: "alice"}, {Name: "bob, jr"}, {Name: "=HYPERLINK(\"http://evil\")"}, {Name: "-12.5"}}}, nil
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+	response.RegisterProtoMapping(&userRes{}, func(payload interface{}) (proto.Message, error) {
+		return wrapperspb.String(payload.(*userRes).Name), nil
+	})
+	client := startServer(t, response.Options{Format: response.FormatJSON, Negotiate: true}, userApi{}, userListApi{})
+	fetch := func(accept string, list bool) ([]byte, string) {
+		var (
+			c   = client.ContentJson().Header(g.MapStrStr{"Accept": accept})
+			res *gclient.Response
+			err error
+		)
+		if list {
+			res, err = c.Get(context.Background(), "/users")
+		} else {
+			res, err = c.Post(context.Background(), "/user", g.Map{"name": "alice"})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Close()
+		return res.ReadAll(), res.Header.Get("Content-Disposition")
+	}
+
+	t.Run("cbor", func(t *testing.T) {
+		var result struct {
+			Ok      bool    `cbor:"ok"`
+			Payload userRes `cbor:"payload"`
+		}
+		b, _ := fetch("application/cbor", false)
+		if err := cbor.Unmarshal(b, &result); err != nil || !result.Ok || result.Payload.Name != "alice" {
+			t.Fatalf("unexpected cbor result %+v, err %v", result, err)
+		}
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		var (
+			b, _    = fetch("application/x-protobuf", false)
+			ok      bool
+			payload = &wrapperspb.StringValue{}
+		)
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			b = b[n:]
+			switch {
+			case num == 1 && typ == protowire.VarintType:
+				v, m := protowire.ConsumeVarint(b)
+				ok, b = protowire.DecodeBool(v), b[m:]
+			case num == 2 && typ == protowire.BytesType:
+				v, m := protowire.ConsumeBytes(b)
+				if err := proto.Unmarshal(v, payload); err != nil {
+					t.Fatal(err)
+				}
+				b = b[m:]
+			default:
+				t.Fatalf("unexpected protobuf field %d", num)
+			}
+		}
+		if !ok || payload.GetValue() != "alice" {
+			t.Fatalf("unexpected protobuf result %v %v", ok, payload)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		b, disposition := fetch("text/csv", true)
+		if !bytes.HasPrefix(b, []byte("\xEF\xBB\xBF")) {
+			t.Fatalf("csv must start with the utf-8 bom: %q", b)
+		}
+		records, err := csv.NewReader(bytes.NewReader(b[3:])).ReadAll()
+		if err != nil || len(records) != 5 || records[0][0] != "name" || records[1][0] != "alice" || records[2][0] != "bob, jr" {
+			t.Fatalf("unexpected csv records %q, err %v", records, err)
+		}
+		// formulas are escaped, negative numbers are kept.
+		if records[3][0] != `'=HYPERLINK("http://evil")` || records[4][0] != "-12.5" {
+			t.Fatalf("unexpected csv cells %q", records[3:])
+		}
+		if disposition != `attachment; filename=users.csv` {
+			t.Fatalf("unexpected content disposition %s", disposition)
+		}
+	})
+}
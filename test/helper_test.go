@@ -0,0 +1,168 @@
+package test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+
+	"github.com/fainc/gfe/helper"
+)
+
+// recordDriver is a gdb driver without database, it records the select statements and returns the preset rows.
+type recordDriver struct {
+	*gdb.Core
+	rec *selectRecord
+}
+
+type selectRecord struct {
+	sql  string
+	args []interface{}
+	rows gdb.Result
+}
+
+type noConnector struct{}
+
+func (noConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("record driver has no database")
+}
+
+func (noConnector) Driver() driver.Driver { return nil }
+
+var record = &selectRecord{}
+
+func init() {
+	_ = gdb.Register("gfe-record", &recordDriver{rec: record})
+}
+
+func (d *recordDriver) New(core *gdb.Core, node *gdb.ConfigNode) (gdb.DB, error) {
+	return &recordDriver{Core: core, rec: d.rec}, nil
+}
+
+func (d *recordDriver) Open(config *gdb.ConfigNode) (*sql.DB, error) {
+	return sql.OpenDB(noConnector{}), nil // sql.OpenDB connects lazily, DoSelect never uses the connection.
+}
+
+func (d *recordDriver) GetChars() (charLeft string, charRight string) {
+	return "`", "`"
+}
+
+func (d *recordDriver) Tables(ctx context.Context, schema ...string) (tables []string, err error) {
+	return nil, nil
+}
+
+func (d *recordDriver) TableFields(ctx context.Context, table string, schema ...string) (map[string]*gdb.TableField, error) {
+	return map[string]*gdb.TableField{}, nil
+}
+
+func (d *recordDriver) DoSelect(ctx context.Context, link gdb.Link, sql string, args ...interface{}) (gdb.Result, error) {
+	d.rec.sql, d.rec.args = sql, args
+	return d.rec.rows, nil
+}
+
+func recordModel(t *testing.T, rows ...gdb.Record) *gdb.Model {
+	db, err := gdb.New(gdb.ConfigNode{Type: "gfe-record"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.sql, record.args, record.rows = "", nil, rows
+	return db.Model("orders")
+}
+
+type orderRow struct {
+	ID        int    `json:"id"`
+	CreatedAt string `json:"createdAt"`
+}
+
+func orderRecord(id int, createdAt string) gdb.Record {
+	return gdb.Record{"id": gvar.New(id), "created_at": gvar.New(createdAt)}
+}
+
+func TestKeysetPaginate(t *testing.T) {
+	var (
+		secret = []byte("cursor-secret")
+		opts   = helper.KeysetOptions{Orders: []helper.KeysetOrder{{Column: "o.created_at", Desc: true}, {Column: "id", Desc: true}}, Secret: secret}
+		items  []orderRow
+	)
+	// page 1 fetches limit+1 rows to know whether more rows exist.
+	m := recordModel(t, orderRecord(9, "2024-05-02"), orderRecord(8, "2024-05-01"), orderRecord(7, "2024-05-01"))
+	res, err := helper.Model(m).KeysetPaginate(helper.CursorReq{Limit: 2}, opts, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.sql != "SELECT * FROM `orders` ORDER BY `o`.`created_at` DESC,`id` DESC LIMIT 3" || !res.HasMore || res.PrevCursor != "" || len(items) != 2 {
+		t.Fatalf("unexpected first page %s %+v", record.sql, res)
+	}
+	// the next cursor carries the sort values of the last row, signed by the secret.
+	parts := strings.Split(res.NextCursor, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if len(parts) != 2 || string(payload) != `{"d":"next","v":["2024-05-01","8"]}` {
+		t.Fatalf("unexpected next cursor %s", payload)
+	}
+	// page 2 filters with the keyset condition.
+	m = recordModel(t, orderRecord(7, "2024-05-01"))
+	res, err = helper.Model(m).KeysetPaginate(helper.CursorReq{Cursor: res.NextCursor, Limit: 2}, opts, &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "SELECT * FROM `orders` WHERE ((o.created_at < ?) OR (o.created_at = ? AND `id` < ?)) ORDER BY `o`.`created_at` DESC,`id` DESC LIMIT 3"
+	if record.sql != expect || fmt.Sprint(record.args) != "[2024-05-01 2024-05-01 8]" || res.HasMore || res.NextCursor != "" || res.PrevCursor == "" {
+		t.Fatalf("unexpected second page %s %v %+v", record.sql, record.args, res)
+	}
+	// the prev cursor reverses the comparison and the order.
+	m = recordModel(t, orderRecord(8, "2024-05-01"))
+	if _, err = helper.Model(m).KeysetPaginate(helper.CursorReq{Cursor: res.PrevCursor, Limit: 2}, opts, &items); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(record.sql, "WHERE ((o.created_at > ?) OR (o.created_at = ? AND `id` > ?)) ORDER BY `o`.`created_at` ASC,`id` ASC") {
+		t.Fatalf("unexpected prev page %s", record.sql)
+	}
+	// tampered, forged or malformed cursors are rejected before querying.
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"d":"next","v":["2099-01-01","1"]}`))
+	mac := hmac.New(sha256.New, []byte("other-secret"))
+	mac.Write([]byte(forged))
+	for _, cursor := range []string{
+		forged + "." + parts[1],
+		forged + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)),
+		parts[0],
+		"not-base64.!",
+	} {
+		m = recordModel(t)
+		if _, err = helper.Model(m).KeysetPaginate(helper.CursorReq{Cursor: cursor, Limit: 2}, opts, &items); err == nil || record.sql != "" {
+			t.Fatalf("cursor %s should be rejected, err %v sql %s", cursor, err, record.sql)
+		}
+	}
+}
+
+type orderFilterReq struct {
+	Remark string `json:"remark" filter:"remark,like"`
+	Sort   string `json:"sort" sort:"createdAt:created_at,amount"`
+}
+
+func TestModelFilter(t *testing.T) {
+	m, err := helper.Model(recordModel(t)).Filter(&orderFilterReq{Remark: `50%_off\`, Sort: "-createdAt, amount"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := helper.KeysetOptions{Orders: []helper.KeysetOrder{{Column: "id"}}, Secret: []byte("cursor-secret")}
+	if _, err = m.KeysetPaginate(helper.CursorReq{Limit: 10}, opts, &[]orderRow{}); err != nil {
+		t.Fatal(err)
+	}
+	if record.sql != "SELECT * FROM `orders` WHERE `remark` LIKE ? ORDER BY `created_at` DESC,`amount` ASC,`id` ASC LIMIT 11" || fmt.Sprint(record.args) != `[%50\%\_off\\%]` {
+		t.Fatalf("unexpected filter sql %s %v", record.sql, record.args)
+	}
+	for _, sort := range []string{"id", "-created_at", "amount,password"} {
+		if _, err = helper.Model(recordModel(t)).Filter(&orderFilterReq{Sort: sort}); err == nil {
+			t.Fatalf("sort %s should be rejected", sort)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/util/guid"
+
+	"github.com/fainc/gfe/middleware"
+	"github.com/fainc/gfe/response"
+)
+
+type orderCreateReq struct {
+	g.Meta `path:"/order" method:"post" x-idempotent:"required"`
+	Amount int
+}
+type orderCreateRes struct {
+	ID     int64 `json:"id"`
+	Amount int   `json:"amount"`
+}
+
+type idempotentApi struct {
+	seq int64
+}
+
+func (a *idempotentApi) Create(ctx context.Context, req *orderCreateReq) (res *orderCreateRes, err error) {
+	return &orderCreateRes{ID: atomic.AddInt64(&a.seq, 1), Amount: req.Amount}, nil
+}
+
+// failStore is an idempotency store which is unavailable.
+type failStore struct{}
+
+func (failStore) Reserve(context.Context, string, *middleware.IdempotencyRecord, time.Duration) (*middleware.IdempotencyRecord, error) {
+	return nil, errors.New("store down")
+}
+
+func (failStore) Save(context.Context, string, *middleware.IdempotencyRecord, time.Duration) error {
+	return errors.New("store down")
+}
+
+func (failStore) Release(context.Context, string) error {
+	return errors.New("store down")
+}
+
+func startIdempotentServer(t *testing.T, api *idempotentApi, opts middleware.IdempotencyOptions, responder *response.Options) func(key string, amount int, acceptEncoding ...string) (status int, replayed, body string) {
+	s := g.Server(guid.S())
+	s.SetDumpRouterMap(false)
+	s.SetAccessLogEnabled(false)
+	s.SetErrorLogEnabled(false)
+	s.SetPort(0)
+	s.Group("/", func(group *ghttp.RouterGroup) {
+		if responder != nil {
+			group.Middleware(response.NewResponder(*responder).Middleware)
+		}
+		group.Middleware(middleware.Idempotency(opts).Register)
+		group.Bind(api)
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown() })
+	time.Sleep(100 * time.Millisecond)
+	client := g.Client()
+	client.SetPrefix(fmt.Sprintf("http://127.0.0.1:%d", s.GetListenedPort()))
+	return func(key string, amount int, acceptEncoding ...string) (status int, replayed, body string) {
+		c := client.ContentJson()
+		if key != "" {
+			c = c.Header(g.MapStrStr{middleware.HeaderIdempotencyKey: key})
+		}
+		if len(acceptEncoding) > 0 {
+			c = c.Header(g.MapStrStr{"Accept-Encoding": acceptEncoding[0]})
+		}
+		res, err := c.Post(context.Background(), "/order", g.Map{"amount": amount})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Close()
+		return res.StatusCode, res.Header.Get(middleware.HeaderIdempotentReplayed), res.ReadAllString()
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	post := startIdempotentServer(t, &idempotentApi{}, middleware.IdempotencyOptions{TTL: time.Minute}, &response.Options{Format: response.FormatJSON})
+	expect := `{"ok":true,"payload":{"id":1,"amount":100}}`
+	if status, replayed, body := post("k1", 100); status != http.StatusOK || replayed != "" || body != expect {
+		t.Fatalf("unexpected first response %d %s %s", status, replayed, body)
+	}
+	if status, replayed, body := post("k1", 100); status != http.StatusOK || replayed != "true" || body != expect {
+		t.Fatalf("unexpected replayed response %d %s %s", status, replayed, body)
+	}
+	if _, _, body := post("k1", 200); body != `{"ok":false,"error":{"code":422,"message":"IdempotencyKeyReused","detail":["k1"]}}` {
+		t.Fatalf("unexpected reused key response %s", body)
+	}
+	if _, _, body := post("", 100); body != `{"ok":false,"error":{"code":-1,"message":"IdempotencyKeyRequired","detail":[]}}` {
+		t.Fatalf("unexpected missing key response %s", body)
+	}
+	if _, _, body := post("k2", 100); body != `{"ok":true,"payload":{"id":2,"amount":100}}` {
+		t.Fatalf("unexpected new key response %s", body)
+	}
+}
+
+func TestIdempotencyWithoutResponder(t *testing.T) {
+	// without the responder the response is not saved, the key is released after the request.
+	api := &idempotentApi{}
+	post := startIdempotentServer(t, api, middleware.IdempotencyOptions{}, nil)
+	for i := 0; i < 2; i++ {
+		if _, replayed, _ := post("k1", 100); replayed != "" {
+			t.Fatal("response must not be replayed without the responder")
+		}
+	}
+	if seq := atomic.LoadInt64(&api.seq); seq != 2 {
+		t.Fatalf("released key should run the handler again, got %d runs", seq)
+	}
+}
+
+func TestIdempotencyStoreError(t *testing.T) {
+	api := &idempotentApi{}
+	post := startIdempotentServer(t, api, middleware.IdempotencyOptions{Store: failStore{}}, &response.Options{Format: response.FormatJSON})
+	status, _, body := post("k1", 100)
+	if status != http.StatusInternalServerError || strings.Contains(body, "store down") || atomic.LoadInt64(&api.seq) != 0 {
+		t.Fatalf("unexpected store error response %d %s", status, body)
+	}
+}
+
+// failSigner fails to sign success bodies, the responder panics after the handler has run.
+type failSigner struct{}
+
+func (failSigner) Alg() string { return "HS256" }
+
+func (failSigner) KeyID() string { return "k1" }
+
+func (failSigner) Sign(data []byte) ([]byte, error) {
+	if strings.Contains(string(data), `"ok":true`) {
+		return nil, errors.New("signer down")
+	}
+	return []byte("signature"), nil
+}
+
+func TestIdempotencyResponderPanic(t *testing.T) {
+	// a response interrupted by a responder panic is never saved, the key expires after the lock ttl.
+	api := &idempotentApi{}
+	opts := middleware.IdempotencyOptions{LockTTL: 300 * time.Millisecond}
+	post := startIdempotentServer(t, api, opts, &response.Options{Format: response.FormatJSON, Signer: failSigner{}})
+	if status, _, _ := post("k1", 100); status != http.StatusInternalServerError {
+		t.Fatalf("unexpected first status %d", status)
+	}
+	if status, replayed, body := post("k1", 100); replayed != "" || !strings.Contains(body, `"code":409`) {
+		t.Fatalf("unexpected retry response %d %s %s", status, replayed, body)
+	}
+	time.Sleep(400 * time.Millisecond)
+	if status, replayed, _ := post("k1", 100); status != http.StatusInternalServerError || replayed != "" || atomic.LoadInt64(&api.seq) != 2 {
+		t.Fatalf("unexpected response after the lock ttl %d %s", status, replayed)
+	}
+}
+
+func TestIdempotencyCompressedReplay(t *testing.T) {
+	// the body is saved before compression, a retry accepting another content coding receives the identity body.
+	responder := &response.Options{Format: response.FormatJSON, Compress: &response.CompressOptions{MinSize: 1}}
+	post := startIdempotentServer(t, &idempotentApi{}, middleware.IdempotencyOptions{}, responder)
+	expect := `{"ok":true,"payload":{"id":1,"amount":100}}`
+	if _, replayed, body := post("k1", 100, "br"); replayed != "" || body == expect {
+		t.Fatalf("first response should be compressed %s %s", replayed, body)
+	}
+	if status, replayed, body := post("k1", 100, "identity"); status != http.StatusOK || replayed != "true" || body != expect {
+		t.Fatalf("unexpected replayed response %d %s %s", status, replayed, body)
+	}
+}
@@ -0,0 +1,786 @@
+package test
+
+import (
+	"compress/zlib"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/gclient"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gfile"
+	"github.com/gogf/gf/v2/os/gview"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/fainc/gfe/crypt"
+	"github.com/fainc/gfe/helper"
+	"github.com/fainc/gfe/response"
+)
+
+type userReq struct {
+	g.Meta `path:"/user" method:"post"`
+	Name   string `v:"required" json:"name"`
+	Phone  string `v:"phone" json:"phone"`
+}
+
+type userRes struct {
+	Name string `json:"name"`
+}
+
+type userApi struct{}
+
+func (userApi) User(ctx context.Context, req *userReq) (res *userRes, err error) {
+	return &userRes{Name: req.Name}, nil
+}
+
+// startServer starts a server with the responder and returns the client prefix of it.
+func startServer(t *testing.T, opts response.Options, bind ...interface{}) *gclient.Client {
+	s := g.Server(guid.S())
+	s.SetDumpRouterMap(false)
+	s.SetAccessLogEnabled(false)
+	s.SetErrorLogEnabled(false)
+	s.SetPort(0)
+	s.Group("/", func(group *ghttp.RouterGroup) {
+		group.Middleware(response.NewResponder(opts).Middleware)
+		group.Bind(bind...)
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown() })
+	time.Sleep(100 * time.Millisecond)
+	client := g.Client()
+	client.SetPrefix(fmt.Sprintf("http://127.0.0.1:%d", s.GetListenedPort()))
+	return client
+}
+
+func TestResponderNegotiate(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON, Negotiate: true}, userApi{})
+	cases := []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json"},
+		{"*/*", http.StatusOK, "application/json"},
+		{"application/json;q=0.5, application/xml", http.StatusOK, "application/xml"},
+		{"application/xml;q=0.2, application/x-msgpack;q=0.8", http.StatusOK, "application/x-msgpack"},
+		{"application/yaml;q=0, application/cbor;q=0.1", http.StatusOK, "application/cbor"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", http.StatusOK, "application/xml"}, // html needs Options.HTML.
+		{"text/plain", http.StatusNotAcceptable, "application/json"},
+		{"application/json;q=0", http.StatusNotAcceptable, "application/json"},
+	}
+	for _, c := range cases {
+		res, err := client.ContentJson().Header(g.MapStrStr{"Accept": c.accept}).Post(context.Background(), "/user", g.Map{"name": "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := res.ReadAllString()
+		res.Close()
+		if res.StatusCode != c.status || !strings.HasPrefix(res.Header.Get("Content-Type"), c.contentType) {
+			t.Fatalf("accept %q: unexpected response %d %s %s", c.accept, res.StatusCode, res.Header.Get("Content-Type"), body)
+		}
+		if c.status == http.StatusNotAcceptable && !strings.Contains(body, `"code":406`) {
+			t.Fatalf("accept %q: unexpected body %s", c.accept, body)
+		}
+	}
+}
+
+func TestResponderStatusPolicy(t *testing.T) {
+	mapper := func(code gcode.Code) int {
+		if code.Code() == 1001 {
+			return http.StatusConflict
+		}
+		return 0
+	}
+	cases := []struct {
+		opts   response.Options
+		expect map[int]int // error code => http status
+	}{
+		{response.Options{StatusPolicy: response.StatusAlways200}, map[int]int{-1: 200, 401: 200, 402: 200, 404: 200, 1001: 200}},
+		{response.Options{StatusPolicy: response.StatusReal4xx}, map[int]int{-1: 200, 401: 401, 402: 402, 404: 404, 1001: 200}},
+		{response.Options{StatusPolicy: response.StatusDeclared}, map[int]int{-1: 400, 401: 401, 402: 400, 403: 403, 404: 404, 420: 400, 1001: 200}},
+		{response.Options{StatusPolicy: response.StatusDeclared, StatusMapper: mapper}, map[int]int{-1: 400, 401: 401, 402: 400, 404: 404, 1001: 409}},
+	}
+	for i, c := range cases {
+		c.opts.Format = response.FormatJSON
+		client := startServer(t, c.opts, failApi{})
+		for code, status := range c.expect {
+			res, err := client.Get(context.Background(), fmt.Sprintf("/fail?code=%d", code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := res.ReadAllString()
+			res.Close()
+			if res.StatusCode != status || !strings.Contains(body, fmt.Sprintf(`"code":%d,`, code)) {
+				t.Fatalf("case %d code %d: unexpected response %d %s", i, code, res.StatusCode, body)
+			}
+		}
+	}
+}
+
+func TestResponderValidationFields(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON}, userApi{})
+	res, err := client.ContentJson().Post(context.Background(), "/user", g.Map{"phone": "123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	body := res.ReadAllString()
+	if res.StatusCode != http.StatusOK || body != `{"ok":false,"error":{"code":51,"message":"The Name field is required","detail":[{"field":"Name","rule":"required","message":"The Name field is required"}]}}` {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, body)
+	}
+}
+
+type failReq struct {
+	g.Meta `path:"/fail" method:"get"`
+	Code   int `json:"code"`
+}
+
+type failApi struct{}
+
+func (failApi) Fail(ctx context.Context, req *failReq) (res *userRes, err error) {
+	switch req.Code {
+	case -1:
+		return nil, response.StandError(ctx, "bad input", "name")
+	case 401:
+		return nil, response.UnAuthorizedError(ctx, "token expired")
+	default:
+		return nil, response.CodeError(req.Code, "failed")
+	}
+}
+
+func TestResponderProblemDetails(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON, ProblemDetails: true, ProblemTypeBase: "https://example.com/problems/"}, userApi{}, failApi{})
+	type problem struct {
+		Type     string                `json:"type"`
+		Title    string                `json:"title"`
+		Status   int                   `json:"status"`
+		Detail   string                `json:"detail"`
+		Instance string                `json:"instance"`
+		Code     int                   `json:"code"`
+		Errors   []response.FieldError `json:"errors"`
+	}
+	cases := []struct {
+		url    string
+		expect problem
+	}{
+		{"/fail?code=-1", problem{Type: "https://example.com/problems/stand-error", Title: "bad input", Status: 400, Detail: "name", Instance: "/fail", Code: -1}},
+		{"/fail?code=401", problem{Type: "https://example.com/problems/unauthorized", Title: "Unauthorized", Status: 401, Detail: "token expired", Instance: "/fail", Code: 401}},
+		{"/fail?code=1001", problem{Type: "https://example.com/problems/business-error", Title: "failed", Status: 400, Instance: "/fail", Code: 1001}},
+		{"/fail?code=404", problem{Type: "about:blank", Title: "failed", Status: 404, Instance: "/fail", Code: 404}},
+		{"/fail?code=403", problem{Type: "about:blank", Title: "failed", Status: 403, Instance: "/fail", Code: 403}},
+		{"/fail?code=420", problem{Type: "https://example.com/problems/decryption-error", Title: "failed", Status: 400, Instance: "/fail", Code: 420}},
+	}
+	for _, c := range cases {
+		res, err := client.Get(context.Background(), c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p problem
+		err = json.Unmarshal(res.ReadAll(), &p)
+		res.Close()
+		if err != nil || res.StatusCode != c.expect.Status || res.Header.Get("Content-Type") != "application/problem+json; charset=utf-8" || fmt.Sprint(p) != fmt.Sprint(c.expect) {
+			t.Fatalf("%s: unexpected problem %d %+v, err %v", c.url, res.StatusCode, p, err)
+		}
+	}
+	res, err := client.ContentJson().Post(context.Background(), "/user", g.Map{"phone": "123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	var p problem
+	if err = json.Unmarshal(res.ReadAll(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusBadRequest || p.Type != "https://example.com/problems/validation-failed" || p.Code != 51 || p.Detail != "" ||
+		len(p.Errors) != 1 || p.Errors[0].Field != "Name" || p.Errors[0].Rule != "required" {
+		t.Fatalf("unexpected validation problem %d %+v", res.StatusCode, p)
+	}
+}
+
+type exportReq struct {
+	g.Meta `path:"/export" method:"get"`
+}
+
+type exportRes struct {
+	*response.Stream
+}
+
+type exportApi struct{}
+
+func (exportApi) Export(ctx context.Context, req *exportReq) (res *exportRes, err error) {
+	items := make(chan interface{})
+	go func() {
+		defer close(items)
+		for i := 1; i <= 2; i++ {
+			items <- g.Map{"row": i}
+		}
+		items <- member{ID: 3, Phone: "13812345678", Email: "bob@example.com", Note: "vip"} // stream items are masked as well.
+		items <- response.StandError(ctx, "export failed")
+	}()
+	s := response.NewStream(response.StreamNDJSON, items)
+	s.Heartbeat = -1
+	return &exportRes{s}, nil
+}
+
+func TestResponderStream(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON}, exportApi{})
+	res, err := client.Get(context.Background(), "/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	body := res.ReadAllString()
+	expect := `{"ok":true,"payload":{"row":1}}` + "\n" + `{"ok":true,"payload":{"row":2}}` + "\n" +
+		`{"ok":true,"payload":{"id":3,"phone":"138****5678","email":"b**@example.com"}}` + "\n" + `{"ok":false,"error":{"code":-1,"message":"export failed","detail":[]}}` + "\n"
+	if res.Header.Get("Content-Type") != "application/x-ndjson; charset=utf-8" || body != expect {
+		t.Fatalf("unexpected response %s %s", res.Header.Get("Content-Type"), body)
+	}
+}
+
+type orderListReq struct {
+	g.Meta `path:"/orders" method:"get" cache-control:"private, max-age=60"`
+	Page   int `json:"page"`
+}
+
+type orderListRes struct {
+	*response.PageRes
+}
+
+type orderApi struct{}
+
+func (orderApi) OrderList(ctx context.Context, req *orderListReq) (res *orderListRes, err error) {
+	return &orderListRes{&response.PageRes{Items: []int{3, 4}, Total: 5, Page: req.Page, PageSize: 2, Pages: 3}}, nil
+}
+
+func TestResponderPage(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON}, orderApi{})
+	res, err := client.Get(context.Background(), "/orders?page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	body := res.ReadAllString()
+	if body != `{"ok":true,"payload":{"items":[3,4],"total":5,"page":2,"pageSize":2,"pages":3}}` || res.Header.Get("X-Total-Count") != "5" {
+		t.Fatalf("unexpected response %s", body)
+	}
+	link := `</orders?page=1&pageSize=2>; rel="first", </orders?page=1&pageSize=2>; rel="prev", </orders?page=3&pageSize=2>; rel="next", </orders?page=3&pageSize=2>; rel="last"`
+	if res.Header.Get("Link") != link {
+		t.Fatalf("unexpected link %s", res.Header.Get("Link"))
+	}
+}
+
+func TestResponderETag(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON, ETag: true}, orderApi{})
+	res, err := client.Get(context.Background(), "/orders?page=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	etag := res.Header.Get("ETag")
+	if res.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, etag)
+	}
+	res, err = client.Header(map[string]string{"If-None-Match": etag}).Get(context.Background(), "/orders?page=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	if res.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", res.StatusCode)
+	}
+}
+
+func TestResponderCacheControl(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON}, orderApi{})
+	res, err := client.Get(context.Background(), "/orders?page=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	if res.Header.Get("Cache-Control") != "private, max-age=60" || res.Header.Get("ETag") != "" {
+		t.Fatalf("unexpected cache headers %v", res.Header)
+	}
+}
+
+func TestResponderCompress(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON, Compress: &response.CompressOptions{MinSize: 1}}, orderApi{})
+	res, err := client.Header(map[string]string{"Accept-Encoding": "deflate, gzip;q=0.8, br;q=0"}).Get(context.Background(), "/orders?page=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if res.Header.Get("Content-Encoding") != "deflate" || res.Header.Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("unexpected headers %v", res.Header)
+	}
+	reader, err := zlib.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(reader)
+	if string(body) != `{"ok":true,"payload":{"items":[3,4],"total":5,"page":1,"pageSize":2,"pages":3}}` {
+		t.Fatalf("unexpected response %s", body)
+	}
+}
+
+type memberListReq struct {
+	g.Meta `path:"/members" method:"get"`
+}
+
+type member struct {
+	ID    int    `json:"id"`
+	Phone string `json:"phone" mask:"phone" visible:"role:admin"`
+	Email string `json:"email" mask:"email"`
+	Note  string `json:"note" visible:"role:admin"`
+}
+
+type memberListRes struct {
+	*response.PageRes
+	Summary string `json:"summary" visible:"role:admin"`
+}
+
+type memberApi struct{}
+
+func (memberApi) MemberList(ctx context.Context, req *memberListReq) (res *memberListRes, err error) {
+	items := []member{{ID: 1, Phone: "13812345678", Email: "alice@example.com", Note: "vip"}}
+	return &memberListRes{&response.PageRes{Items: items, Total: 1, Page: 1, PageSize: 10, Pages: 1}, "1 member"}, nil
+}
+
+func TestResponderMask(t *testing.T) {
+	roles := func(ctx context.Context) []string {
+		return []string{g.RequestFromCtx(ctx).GetHeader("X-Role")}
+	}
+	client := startServer(t, response.Options{Format: response.FormatJSON, Roles: roles, Projection: true}, memberApi{})
+	for _, c := range []struct{ role, query, expect string }{
+		{"", "", `{"ok":true,"payload":{"items":[{"id":1,"phone":"138****5678","email":"a****@example.com"}],"total":1,"page":1,"pageSize":10,"pages":1}}`},
+		{"admin", "", `{"ok":true,"payload":{"items":[{"id":1,"phone":"13812345678","email":"a****@example.com","note":"vip"}],"total":1,"page":1,"pageSize":10,"pages":1,"summary":"1 member"}}`},
+		{"admin", "?fields=id,note", `{"ok":true,"payload":{"items":[{"id":1,"note":"vip"}],"total":1,"page":1,"pageSize":10,"pages":1}}`},
+	} {
+		res, err := client.Header(map[string]string{"X-Role": c.role}).Get(context.Background(), "/members"+c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := res.ReadAllString()
+		res.Close()
+		if body != c.expect {
+			t.Fatalf("unexpected response %s", body)
+		}
+	}
+}
+
+type memberCardReq struct {
+	g.Meta `path:"/member-card" method:"get"`
+}
+
+// MemberLevel embeds an interface with methods, which cannot be rebuilt by reflect.StructOf.
+type MemberLevel struct {
+	io.Reader
+	Level int `json:"level"`
+}
+
+type memberCardRes struct {
+	Phone string `json:"phone" mask:"phone"`
+	MemberLevel
+}
+
+func (memberApi) MemberCard(ctx context.Context, req *memberCardReq) (res *memberCardRes, err error) {
+	return &memberCardRes{Phone: "13812345678", MemberLevel: MemberLevel{Level: 3}}, nil
+}
+
+func TestResponderMaskEmbeddedInterface(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON}, memberApi{})
+	res, err := client.Get(context.Background(), "/member-card")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if body := res.ReadAllString(); body != `{"ok":true,"payload":{"phone":"138****5678","Reader":null,"level":3}}` {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, body)
+	}
+}
+
+func TestResponderSigner(t *testing.T) {
+	pri, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := startServer(t, response.Options{Format: response.FormatJSON, Signer: crypt.NewECDSASigner(pri, "k1")}, orderApi{})
+	res, err := client.Get(context.Background(), "/orders?page=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	body := res.ReadAll()
+	verifier := crypt.NewECDSAVerifier(&pri.PublicKey, "k1")
+	if err = crypt.VerifyResponse(res.Response, body, time.Minute, verifier); err != nil {
+		t.Fatal(err)
+	}
+	if err = crypt.VerifyResponse(res.Response, append(body, ' '), time.Minute, verifier); err == nil {
+		t.Fatal("expected tampered body to fail")
+	}
+	if err = crypt.VerifyResponse(res.Response, body, time.Minute, crypt.NewHMAC([]byte("secret"), "k1")); err == nil {
+		t.Fatal("expected algorithm mismatch to fail")
+	}
+	// a signed body replayed as the answer of another request target or status is rejected.
+	replayed := *res.Response
+	replayed.Request = res.Request.Clone(context.Background())
+	replayed.Request.URL.RawQuery = "page=2"
+	if err = crypt.VerifyResponse(&replayed, body, time.Minute, verifier); err == nil {
+		t.Fatal("expected replay to another request target to fail")
+	}
+	replayed = *res.Response
+	replayed.StatusCode = http.StatusCreated
+	if err = crypt.VerifyResponse(&replayed, body, time.Minute, verifier); err == nil {
+		t.Fatal("expected replay with another status to fail")
+	}
+}
+
+type rawUserReq struct {
+	g.Meta `path:"/raw-user" method:"post" format:"yaml" envelope:"none"`
+	Name   string `v:"required" json:"name"`
+}
+
+func (userApi) RawUser(ctx context.Context, req *rawUserReq) (res *userRes, err error) {
+	return &userRes{Name: req.Name}, nil
+}
+
+type secureUserReq struct {
+	g.Meta `path:"/secure-user" method:"post" encrypt:"true"`
+	Name   string `v:"required" json:"name"`
+}
+
+func (userApi) SecureUser(ctx context.Context, req *secureUserReq) (res *userRes, err error) {
+	return &userRes{Name: req.Name}, nil
+}
+
+func TestResponderMeta(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON}, userApi{})
+	res, err := client.Post(context.Background(), "/raw-user", g.Map{"name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if body := res.ReadAllString(); body != "name: alice\n" || res.Header.Get("Content-Type") != "application/yaml; charset=utf-8" {
+		t.Fatalf("unexpected response %s %s", res.Header.Get("Content-Type"), body)
+	}
+	// a route declaring encryption never writes the payload in clear, ECIES requires the client key.
+	secure, err := client.ContentJson().Post(context.Background(), "/secure-user", g.Map{"name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secure.Close()
+	if body := secure.ReadAllString(); body != `{"ok":false,"error":{"code":420,"message":"DecryptionError","detail":["client key required"]}}` {
+		t.Fatalf("unexpected response without client key %s", body)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := crypt.ClientPublicKeyHeader(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := client.ContentJson().Header(map[string]string{crypt.HeaderClientPublicKey: header}).Post(context.Background(), "/secure-user", g.Map{"name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer encrypted.Close()
+	var (
+		result struct {
+			Ok        bool            `json:"ok"`
+			Payload   *crypt.Envelope `json:"payload"`
+			Encrypted bool            `json:"encrypted"`
+		}
+		user userRes
+	)
+	if err = json.Unmarshal(encrypted.ReadAll(), &result); err != nil || !result.Ok || !result.Encrypted {
+		t.Fatalf("unexpected encrypted response %+v, err %v", result, err)
+	}
+	if err = crypt.Open(crypt.NewECDHDecryptor(key, ""), result.Payload, &user); err != nil || user.Name != "alice" {
+		t.Fatalf("unexpected decrypted payload %+v, err %v", user, err)
+	}
+}
+
+func TestResponderLegacyEncryptor(t *testing.T) {
+	encryptor := func(payload interface{}) (interface{}, bool) {
+		return "sealed", true
+	}
+	client := startServer(t, response.Options{Format: response.FormatJSON, Encryptor: encryptor}, userApi{})
+	res, err := client.ContentJson().Post(context.Background(), "/user", g.Map{"name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if body := res.ReadAllString(); body != `{"ok":true,"payload":"sealed","encrypted":true}` {
+		t.Fatalf("unexpected response %s", body)
+	}
+}
+
+type failSealer struct{}
+
+func (failSealer) Seal(plain []byte) (*crypt.Envelope, error) {
+	return nil, errors.New("sealer key unavailable")
+}
+
+func TestResponderEncryptorError(t *testing.T) {
+	// a malformed client key is a client error, the response is never written in clear.
+	client := startServer(t, response.Options{Format: response.FormatJSON, CtxEncryptor: response.ECIESEncryptor()}, userApi{})
+	res, err := client.ContentJson().Header(map[string]string{crypt.HeaderClientPublicKey: "bm90LWEta2V5"}).Post(context.Background(), "/user", g.Map{"name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := res.ReadAllString()
+	res.Close()
+	if res.StatusCode != http.StatusOK || body != `{"ok":false,"error":{"code":420,"message":"DecryptionError","detail":["invalid client public key"]}}` {
+		t.Fatalf("unexpected malformed key response %d %s", res.StatusCode, body)
+	}
+	// a sealer failure is written as InternalError through the envelope.
+	client = startServer(t, response.Options{Format: response.FormatJSON, CtxEncryptor: response.SealEncryptor(failSealer{})}, userApi{})
+	res, err = client.ContentJson().Post(context.Background(), "/user", g.Map{"name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = res.ReadAllString()
+	res.Close()
+	if res.StatusCode != http.StatusInternalServerError || !strings.HasPrefix(body, `{"ok":false,"error":{"code":500,"message":"Internal Server Error"`) || strings.Contains(body, "alice") {
+		t.Fatalf("unexpected sealer error response %d %s", res.StatusCode, body)
+	}
+}
+
+type downloadReq struct {
+	g.Meta `path:"/download" method:"get"`
+}
+
+type downloadRes struct{}
+
+type downloadApi struct{}
+
+func (downloadApi) Download(ctx context.Context, req *downloadReq) (res *downloadRes, err error) {
+	err = response.ServeContent(ctx, strings.NewReader("hello world"), time.Now(), response.FileOptions{Name: "报表.txt", Checksum: true})
+	return
+}
+
+type legacyDownloadReq struct {
+	g.Meta `path:"/legacy-download" method:"get"`
+}
+
+func (downloadApi) LegacyDownload(ctx context.Context, req *legacyDownloadReq) (res *downloadRes, err error) {
+	r := g.RequestFromCtx(ctx)
+	r.Response.Header().Set("Content-Type", response.ContentTypeForceDownload)
+	r.Response.Write("hello world")
+	return
+}
+
+func TestResponderLegacyDownload(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON}, downloadApi{})
+	res, err := client.Get(context.Background(), "/legacy-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if body := res.ReadAllString(); body != "hello world" || res.Header.Get("Content-Type") != response.ContentTypeForceDownload {
+		t.Fatalf("unexpected legacy download %s %s", res.Header.Get("Content-Type"), body)
+	}
+}
+
+func TestResponderServeContent(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON}, downloadApi{})
+	res, err := client.Header(map[string]string{"Range": "bytes=6-"}).Get(context.Background(), "/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if body := res.ReadAllString(); res.StatusCode != http.StatusPartialContent || body != "world" {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, body)
+	}
+	if res.Header.Get("Content-Disposition") != `attachment; filename*=utf-8''%E6%8A%A5%E8%A1%A8.txt` || res.Header.Get("Repr-Digest") != "sha-256=:uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=:" {
+		t.Fatalf("unexpected headers %v", res.Header)
+	}
+}
+
+type profileReq struct {
+	g.Meta `path:"/profile" method:"get" template:"profile.html"`
+	Name   string `v:"required" json:"name"`
+}
+
+func (userApi) Profile(ctx context.Context, req *profileReq) (res *userRes, err error) {
+	return &userRes{Name: req.Name}, nil
+}
+
+func TestResponderHTML(t *testing.T) {
+	dir := t.TempDir()
+	if err := gfile.PutContents(filepath.Join(dir, "profile.html"), "<p>{{.payload.Name}}</p>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gfile.PutContents(filepath.Join(dir, "51.html"), "<p>{{.error.Code}} {{.error.Message}}</p>"); err != nil {
+		t.Fatal(err)
+	}
+	opts := response.Options{Format: response.FormatJSON, Negotiate: true, HTML: &response.HTMLOptions{View: gview.New(dir), ErrorTemplates: map[int]string{51: "51.html"}}}
+	client := startServer(t, opts, userApi{}, failApi{}).Header(map[string]string{"Accept": "text/html"})
+	for query, expect := range map[string]string{"?name=alice": "<p>alice</p>", "": "<p>51 The Name field is required</p>"} {
+		res, err := client.Get(context.Background(), "/profile"+query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := res.ReadAllString()
+		res.Close()
+		if body != expect || res.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+			t.Fatalf("unexpected response %s %s", res.Header.Get("Content-Type"), body)
+		}
+	}
+	// the built-in error page shows the status of the error, not the http 200 written under StatusAlways200.
+	res, err := client.Get(context.Background(), "/fail?code=401")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if body := res.ReadAllString(); res.StatusCode != http.StatusOK || !strings.Contains(body, "<title>401 Unauthorized</title>") {
+		t.Fatalf("unexpected error page %d %s", res.StatusCode, body)
+	}
+}
+
+type panicReq struct {
+	g.Meta `path:"/panic" method:"get"`
+}
+
+type panicApi struct{}
+
+func (panicApi) Panic(ctx context.Context, req *panicReq) (res *userRes, err error) {
+	panic("boom")
+}
+
+func TestResponderPanic(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON, StatusPolicy: response.StatusReal4xx}, panicApi{})
+	var result struct {
+		Ok    bool `json:"ok"`
+		Error struct {
+			Code   int `json:"code"`
+			Detail []struct {
+				ErrorID string `json:"errorId"`
+				Stack   string `json:"stack"`
+			} `json:"detail"`
+		} `json:"error"`
+	}
+	res, err := client.Get(context.Background(), "/panic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if err = json.Unmarshal(res.ReadAll(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusInternalServerError || result.Error.Code != 500 || len(result.Error.Detail) != 1 || result.Error.Detail[0].ErrorID != res.Header.Get("Trace-Id") || result.Error.Detail[0].Stack != "" {
+		t.Fatalf("unexpected response %d %+v", res.StatusCode, result)
+	}
+}
+
+func TestResponderDetailPolicy(t *testing.T) {
+	redact := func(code int, detail interface{}) interface{} {
+		return []string{"redacted"}
+	}
+	client := startServer(t, response.Options{Format: response.FormatJSON, Development: true, Redact: redact}, panicApi{})
+	var result struct {
+		Error struct {
+			Message string   `json:"message"`
+			Detail  []string `json:"detail"`
+			Chain   []string `json:"chain"`
+			Stack   string   `json:"stack"`
+		} `json:"error"`
+	}
+	res, err := client.Get(context.Background(), "/panic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if err = json.Unmarshal(res.ReadAll(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Error.Message, "boom") || len(result.Error.Chain) == 0 || result.Error.Stack == "" || len(result.Error.Detail) != 1 || result.Error.Detail[0] != "redacted" {
+		t.Fatalf("unexpected error %+v", result.Error)
+	}
+}
+
+func TestResponderEnvelope(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON, Negotiate: true, Envelope: response.CodeMsgDataEnvelope}, userApi{})
+	for accept, expect := range map[string]string{
+		"application/json": `{"code":0,"msg":"ok","data":{"name":"alice"}}`,
+		"application/xml":  `<xml><code>0</code><data><name>alice</name></data><msg>ok</msg></xml>`,
+	} {
+		res, err := client.Header(map[string]string{"Accept": accept}).Post(context.Background(), "/user", g.Map{"name": "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := res.ReadAllString()
+		res.Close()
+		if body != expect {
+			t.Fatalf("unexpected response %s", body)
+		}
+	}
+	res, err := client.Header(map[string]string{"Accept": "application/x-msgpack"}).Post(context.Background(), "/user", g.Map{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	var result struct {
+		Code int    `msgpack:"code"`
+		Msg  string `msgpack:"msg"`
+	}
+	if err = msgpack.Unmarshal(res.ReadAll(), &result); err != nil || result.Code != 51 || result.Msg == "" {
+		t.Fatalf("unexpected result %+v, err %v", result, err)
+	}
+}
+
+type traceReq struct {
+	g.Meta `path:"/trace" method:"get"`
+}
+
+type traceRes struct {
+	TraceID string `json:"traceId"`
+}
+
+type traceApi struct{}
+
+func (traceApi) Trace(ctx context.Context, req *traceReq) (res *traceRes, err error) {
+	return &traceRes{TraceID: gctx.CtxId(ctx)}, nil
+}
+
+func TestBatch(t *testing.T) {
+	client := startServer(t, response.Options{Format: response.FormatJSON}, userApi{}, orderApi{}, traceApi{}, helper.Batch(helper.BatchOptions{Concurrency: 2}))
+	res, err := client.ContentJson().Post(context.Background(), "/batch", g.Map{"requests": g.Array{
+		g.Map{"method": "POST", "path": "/user", "body": g.Map{"name": "alice"}},
+		g.Map{"method": "GET", "path": "/orders?page=2"},
+		g.Map{"method": "POST", "path": "/user", "body": g.Map{}},
+		g.Map{"method": "POST", "path": "/batch"},
+		g.Map{"method": "POST", "path": "/batch/", "body": g.Map{"requests": g.Array{g.Map{"method": "GET", "path": "/orders"}}}},
+		g.Map{"method": "GET", "path": "/trace"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	expect := `{"ok":true,"payload":{"responses":[` +
+		`{"status":200,"body":{"ok":true,"payload":{"name":"alice"}}},` +
+		`{"status":200,"body":{"ok":true,"payload":{"items":[3,4],"total":5,"page":2,"pageSize":2,"pages":3}}},` +
+		`{"status":200,"body":{"ok":false,"error":{"code":51,"message":"The Name field is required","detail":[{"field":"Name","rule":"required","message":"The Name field is required"}]}}},` +
+		`{"status":400,"body":"invalid batch path"},` +
+		`{"status":200,"body":{"ok":false,"error":{"code":-1,"message":"NestedBatchRequest","detail":[]}}},` +
+		`{"status":200,"body":{"ok":true,"payload":{"traceId":"` + res.Header.Get("Trace-Id") + `"}}}]}}` // sub-requests keep the trace id of the batch request.
+	if body := res.ReadAllString(); body != expect || res.Header.Get("Trace-Id") == "" {
+		t.Fatalf("unexpected response %s", body)
+	}
+}